@@ -18,27 +18,49 @@ func Bind(items []ast.Item, sess *session.Session) (map[string]*bir.Class, map[s
 	classes := scope.Classes()
 	b := new(sess, scope)
 	for _, fn := range fns {
+		b.tyParams = fn.TyParams
 		b.bindFnDecl(fn, sess, scope)
 	}
 
+	for _, class := range classes {
+		b.class = class
+		for _, method := range class.Methods {
+			b.tyParams = concatTys(class.TyParams, method.TyParams)
+			b.bindFnDecl(method, sess, scope)
+		}
+		b.class = nil
+	}
+	b.tyParams = nil
+
 	return classes, fns
 }
 
 func bindGlobalScope(aItems []ast.Item, sess *session.Session) *Scope {
 	scope := NewScope()
 	b := new(sess, scope)
+	var fns []*bir.Fn
+	var classes []*bir.Class
+	var enums []*bir.Enum
 	for _, aItem := range aItems {
 		switch aItem := aItem.(type) {
 		case *ast.FnDecl:
-			fn := &bir.Fn{Decl: aItem, Out: b.lookupTy(aItem.Out)}
+			fn := &bir.Fn{Decl: aItem, TyParams: b.bindTyParams(aItem.TyParams)}
 			if _, exists := scope.Insert(aItem.Ident.Name, fn); exists {
 				b.error(aItem.Ident.Sp, "function `%s` already exists", aItem.Ident.Name)
 			}
+			fns = append(fns, fn)
 		case *ast.ClassDecl:
-			class := &bir.Class{Decl: aItem, Fields: b.bindFields(aItem.Fields)}
+			class := &bir.Class{Decl: aItem, TyParams: b.bindTyParams(aItem.TyParams)}
 			if _, exists := scope.Insert(aItem.Ident.Name, class); exists {
 				b.error(aItem.Ident.Sp, "class `%s` already exists", aItem.Ident.Name)
 			}
+			classes = append(classes, class)
+		case *ast.EnumDecl:
+			enum := &bir.Enum{Decl: aItem}
+			if _, exists := scope.Insert(aItem.Ident.Name, enum); exists {
+				b.error(aItem.Ident.Sp, "enum `%s` already exists", aItem.Ident.Name)
+			}
+			enums = append(enums, enum)
 		case *ast.ErrItem:
 			continue
 		default:
@@ -46,6 +68,27 @@ func bindGlobalScope(aItems []ast.Item, sess *session.Session) *Scope {
 		}
 	}
 
+	// Signatures are resolved after all items have been inserted,
+	// so that they can refer to items declared after them.
+	for _, fn := range fns {
+		b.tyParams = fn.TyParams
+		fn.In = b.bindParams(fn.Decl.In)
+		fn.Out = b.lookupTy(fn.Decl.Out)
+	}
+
+	for _, class := range classes {
+		b.class = class
+		b.tyParams = class.TyParams
+		class.Fields = b.bindFields(class.Decl.Fields)
+		class.Methods = b.bindMethods(class.Decl.Methods)
+		b.class = nil
+	}
+	b.tyParams = nil
+
+	for _, enum := range enums {
+		enum.Variants = b.bindVariants(enum.Decl.Variants)
+	}
+
 	for _, intr := range ir.Intrinsics() {
 		scope.Insert(intr.String(), (bir.Intrinsic)(intr))
 	}
@@ -55,17 +98,17 @@ func bindGlobalScope(aItems []ast.Item, sess *session.Session) *Scope {
 
 func (b *binder) bindFnDecl(fn *bir.Fn, sess *session.Session, scope *Scope) {
 	prev := b.scope
-	b.fn = fn
+	b.out = fn.Out
 	b.scope = WithOuter(b.scope)
-	fn.In = b.bindParams(fn.Decl.In)
+	for _, param := range fn.In {
+		b.scope.Insert(param.Ident.Name, param)
+	}
+
 	var ty *bir.Ty
 	if fn.Decl.Out == nil {
 		ty = bir.BasicTys[bir.TyUnit]
 	} else {
-		ty = b.lookupTy(fn.Decl.Out)
-		if ty.IsErr() {
-			b.error(fn.Decl.Out.Sp, "cannot find type `%s` in this scope", fn.Decl.Out)
-		}
+		ty = fn.Out
 	}
 
 	body := b.bindExpr(fn.Decl.Body)
@@ -77,8 +120,16 @@ func (b *binder) bindFnDecl(fn *bir.Fn, sess *session.Session, scope *Scope) {
 				ty,
 			)
 			body = &bir.ErrExpr{}
+		} else if bodyTy := blk.Type(); !ty.IsUnit() && !bodyTy.IsErr() && !diverges(blk) && !ty.Accepts(bodyTy) {
+			b.error(
+				fn.Decl.Out.Sp,
+				"expected this function to return `%s`, but got `%s`",
+				ty,
+				bodyTy,
+			)
+			body = &bir.ErrExpr{}
 		}
-	} else if ty != body.Type() {
+	} else if !ty.Accepts(body.Type()) {
 		if fn.Decl.Out != nil {
 			b.error(
 				fn.Decl.Out.Sp,
@@ -99,10 +150,14 @@ func (b *binder) bindFnDecl(fn *bir.Fn, sess *session.Session, scope *Scope) {
 }
 
 type binder struct {
-	sess      *session.Session
-	loopLevel int
-	fn        *bir.Fn
-	scope     *Scope
+	sess  *session.Session
+	loops []*bir.ForExpr // Enclosing loops, innermost last.
+	out   *bir.Ty        // The return type of the enclosing function or closure.
+	class *bir.Class     // The class whose methods are being bound, if any.
+	scope *Scope
+	// Type parameters of the item being bound, including those of
+	// the class when binding a method.
+	tyParams []*bir.Ty
 }
 
 func new(sess *session.Session, scope *Scope) binder {
@@ -115,21 +170,36 @@ func new(sess *session.Session, scope *Scope) binder {
 func (b *binder) bindParams(aParams []*ast.VarDecl) []*bir.VarDecl {
 	var params []*bir.VarDecl
 	seen := make(map[string]bool, len(aParams))
-	for _, aParam := range aParams {
-		if ok := seen[aParam.Ident.Name]; ok {
+	for i, aParam := range aParams {
+		if aParam.Ty.Kind == ast.TySelf && (b.class == nil || i != 0) {
+			b.error(aParam.Ident.Sp, "`self` is only allowed as the first parameter of a method")
+		} else if ok := seen[aParam.Ident.Name]; ok {
 			b.error(aParam.Ident.Sp, "parameter `%s` already exists", aParam.Ident.Name)
 		} else {
 			seen[aParam.Ident.Name] = true
 			param := b.bindVarDecl(aParam)
 			if param != nil {
 				params = append(params, param)
-				b.scope.Insert(aParam.Ident.Name, param)
 			}
 		}
 	}
 	return params
 }
 
+func (b *binder) bindTyParams(idents []*ast.Ident) []*bir.Ty {
+	var params []*bir.Ty
+	seen := make(map[string]bool, len(idents))
+	for _, ident := range idents {
+		if seen[ident.Name] {
+			b.error(ident.Sp, "type parameter `%s` already exists", ident.Name)
+			continue
+		}
+		seen[ident.Name] = true
+		params = append(params, bir.NewParam((*ir.Ident)(ident)))
+	}
+	return params
+}
+
 func (b *binder) bindFields(aFields []*ast.VarDecl) []*bir.VarDecl {
 	var fields []*bir.VarDecl
 	seen := make(map[string]bool, len(aFields))
@@ -145,10 +215,64 @@ func (b *binder) bindFields(aFields []*ast.VarDecl) []*bir.VarDecl {
 	return fields
 }
 
+func (b *binder) bindMethods(aMethods []*ast.FnDecl) []*bir.Fn {
+	var methods []*bir.Fn
+	seen := make(map[string]bool, len(aMethods))
+	for _, aMethod := range aMethods {
+		name := aMethod.Ident.Name
+		if ok := seen[name]; ok {
+			b.error(aMethod.Ident.Sp, "method `%s` already exists", name)
+			continue
+		}
+		seen[name] = true
+
+		if b.class.Field(name) != nil {
+			b.error(aMethod.Ident.Sp, "field `%s` already exists", name)
+			continue
+		}
+
+		if len(aMethod.In) == 0 || aMethod.In[0].Ty.Kind != ast.TySelf {
+			b.error(aMethod.Ident.Sp, "method `%s` must take `self` as its first parameter", name)
+			continue
+		}
+
+		tyParams := b.bindTyParams(aMethod.TyParams)
+		prev := b.tyParams
+		b.tyParams = concatTys(b.class.TyParams, tyParams)
+		methods = append(methods, &bir.Fn{
+			Decl:     aMethod,
+			TyParams: tyParams,
+			In:       b.bindParams(aMethod.In),
+			Out:      b.lookupTy(aMethod.Out),
+		})
+		b.tyParams = prev
+	}
+	return methods
+}
+
+func (b *binder) bindVariants(aVariants []*ast.Variant) []*bir.Variant {
+	var variants []*bir.Variant
+	seen := make(map[string]bool, len(aVariants))
+	for _, aVariant := range aVariants {
+		name := aVariant.Ident.Name
+		if seen[name] {
+			b.error(aVariant.Ident.Sp, "variant `%s` already exists", name)
+			continue
+		}
+		seen[name] = true
+
+		variant := &bir.Variant{Ident: (*ir.Ident)(aVariant.Ident)}
+		for _, aTy := range aVariant.Tys {
+			variant.Tys = append(variant.Tys, b.lookupTy(aTy))
+		}
+		variants = append(variants, variant)
+	}
+	return variants
+}
+
 func (b *binder) bindVarDecl(decl *ast.VarDecl) *bir.VarDecl {
 	ty := b.lookupTy(decl.Ty)
 	if ty.IsErr() {
-		b.error(decl.Ty.Sp, "cannot find type `%s` in this scope", decl.Ty)
 		return nil
 	}
 	return &bir.VarDecl{Ident: (*ir.Ident)(decl.Ident), Ty: ty}
@@ -158,6 +282,10 @@ func (b *binder) bindExpr(expr ast.Expr) bir.Expr {
 	switch expr := expr.(type) {
 	case *ast.Ident:
 		if d, ok := b.scope.Get(expr.Name); ok {
+			// The let of the variable has failed and been reported.
+			if decl, isVar := d.(*bir.VarDecl); isVar && decl.Ty.IsErr() {
+				return &bir.ErrExpr{}
+			}
 			return d
 		}
 		b.error(expr.Sp, "could not find anything named `%s` in this scope", expr.Name)
@@ -168,16 +296,34 @@ func (b *binder) bindExpr(expr ast.Expr) bir.Expr {
 		}
 		b.error(expr.Sp, "`%s` is not valid integer", expr.V)
 		return &bir.ErrExpr{}
+	case *ast.FloatLiteral:
+		if v, err := strconv.ParseFloat(expr.V, 64); err == nil {
+			return &bir.FloatLiteral{V: v}
+		}
+		b.error(expr.Sp, "`%s` is not valid float", expr.V)
+		return &bir.ErrExpr{}
 	case *ast.BooleanLiteral:
 		return &bir.BooleanLiteral{V: expr.V}
 	case *ast.StringLiteral:
 		return &bir.StringLiteral{V: expr.V}
+	case *ast.InterpExpr:
+		return b.bindInterpExpr(expr)
+	case *ast.NilLiteral:
+		return &bir.NilLiteral{}
+	case *ast.UnaryExpr:
+		return b.bindUnaryExpr(expr)
 	case *ast.BinaryExpr:
 		return b.bindBinaryExpr(expr)
 	case *ast.LetExpr:
 		return b.bindLetExpr(expr)
 	case *ast.AssignExpr:
 		return b.bindAssignExpr(expr)
+	case *ast.CompoundAssignExpr:
+		return b.bindCompoundAssignExpr(expr)
+	case *ast.FnExpr:
+		return b.bindFnExpr(expr)
+	case *ast.MatchExpr:
+		return b.bindMatchExpr(expr)
 	case *ast.IfExpr:
 		return b.bindIfExpr(expr)
 	case *ast.BlockExpr:
@@ -190,12 +336,20 @@ func (b *binder) bindExpr(expr ast.Expr) bir.Expr {
 		return b.bindFieldExpr(expr)
 	case *ast.ArrayExpr:
 		return b.bindArrayExpr(expr)
+	case *ast.MapExpr:
+		return b.bindMapExpr(expr)
+	case *ast.TupleExpr:
+		return b.bindTupleExpr(expr)
 	case *ast.IndexExpr:
 		return b.bindIndexExpr(expr)
+	case *ast.RangeExpr:
+		return b.bindRangeExpr(expr)
 	case *ast.ForExpr:
 		return b.bindForExpr(expr)
 	case *ast.BreakExpr:
 		return b.bindBreakExpr(expr)
+	case *ast.ContinueExpr:
+		return b.bindContinueExpr(expr)
 	case *ast.ReturnExpr:
 		return b.bindReturnExpr(expr)
 	case *ast.ErrExpr:
@@ -204,83 +358,397 @@ func (b *binder) bindExpr(expr ast.Expr) bir.Expr {
 	panic("unreachable")
 }
 
-func (b *binder) bindBinaryExpr(expr *ast.BinaryExpr) bir.Expr {
+// bindInterpExpr binds an interpolated string. Any value but `()` can be
+// interpolated, and is formatted the same way as by `println`.
+func (b *binder) bindInterpExpr(expr *ast.InterpExpr) bir.Expr {
+	interp := &bir.InterpExpr{}
+	hasError := false
+	for _, aPart := range expr.Parts {
+		part := b.bindExpr(aPart)
+		if isErr(part) {
+			hasError = true
+			continue
+		}
+		if part.Type().IsUnit() {
+			b.error(aPart.Span(), "cannot interpolate `%s`, which has no value", part.Type())
+			hasError = true
+			continue
+		}
+		interp.Parts = append(interp.Parts, part)
+	}
+
+	if hasError {
+		return &bir.ErrExpr{}
+	}
+	return interp
+}
+
+func (b *binder) bindUnaryExpr(expr *ast.UnaryExpr) bir.Expr {
 	x := b.bindExpr(expr.X)
-	y := b.bindExpr(expr.Y)
-	if isErr(x) || isErr(y) {
+	if isErr(x) {
 		return &bir.ErrExpr{}
 	}
-	op, ok := bir.BindBinOp(expr.Op.Kind, x.Type().Kind, y.Type().Kind)
+	op, ok := bir.BindUnOp(expr.Op.Kind, x.Type().Kind)
 
 	if !ok {
 		sp := expr.Op.Sp
 		switch expr.Op.Kind {
-		case ast.Add:
-			b.error(sp, "cannot add `%s` to `%s`", x.Type(), y.Type())
-		case ast.Sub:
-			b.error(sp, "cannot subtract `%s` from `%s`", y.Type(), x.Type())
-		case ast.Mul:
-			b.error(sp, "cannot multiply `%s` by `%s`", x.Type(), y.Type())
-		case ast.Div:
-			b.error(sp, "cannot divide `%s` by `%s`", x.Type(), y.Type())
-		case ast.Gt, ast.Lt, ast.Ge, ast.Le, ast.Eq, ast.Ne:
-			b.error(sp, "cannot compare `%s` with `%s`", x.Type(), y.Type())
+		case ast.Neg:
+			b.error(sp, "cannot negate `%s`", x.Type())
+		case ast.Not:
+			b.error(sp, "cannot apply `!` to `%s`", x.Type())
 		default:
 			panic("unreachable")
 		}
 		return &bir.ErrExpr{}
 	}
 
-	return &bir.BinaryExpr{X: x, Op: op, Y: y}
+	return &bir.UnaryExpr{Op: op, X: x}
+}
+
+func (b *binder) bindBinaryExpr(expr *ast.BinaryExpr) bir.Expr {
+	x := b.bindExpr(expr.X)
+
+	// The right operand of `&&` is only evaluated if the left one is true,
+	// and that of `||` if it is false, which may narrow optional variables.
+	prev := b.scope
+	switch expr.Op.Kind {
+	case ast.And:
+		b.scope = WithOuter(b.scope)
+		b.narrow(b.narrowings(expr.X, true))
+	case ast.Or:
+		b.scope = WithOuter(b.scope)
+		b.narrow(b.narrowings(expr.X, false))
+	}
+	y := b.bindExpr(expr.Y)
+	b.scope = prev
+
+	if isErr(x) || isErr(y) {
+		return &bir.ErrExpr{}
+	}
+
+	if expr.Op.Kind == ast.In {
+		return b.bindInExpr(expr, x, y)
+	}
+
+	if op, ok := b.bindEqualityOp(expr.Op.Kind, x.Type(), y.Type()); ok {
+		return &bir.BinaryExpr{X: x, Op: op, Y: y, Sp: expr.Sp}
+	}
+	op, ok := bir.BindBinOp(expr.Op.Kind, x.Type().Kind, y.Type().Kind)
+
+	if !ok {
+		b.binOpError(expr.Op, x.Type(), y.Type())
+		return &bir.ErrExpr{}
+	}
+
+	return &bir.BinaryExpr{X: x, Op: op, Y: y, Sp: expr.Sp}
+}
+
+// bindInExpr binds `key in map`, where x is the bound key and y the map.
+func (b *binder) bindInExpr(expr *ast.BinaryExpr, x, y bir.Expr) bir.Expr {
+	if !y.Type().IsMap() {
+		b.error(expr.Y.Span(), "expected a map, but got `%s`", y.Type())
+		return &bir.ErrExpr{}
+	}
+	if !y.Type().Key.Accepts(x.Type()) {
+		b.error(expr.X.Span(), "expected `%s`, but got `%s`", y.Type().Key, x.Type())
+		return &bir.ErrExpr{}
+	}
+
+	op := bir.BinOp{Kind: bir.In, Ty: bir.BasicTys[bir.TyBool]}
+	return &bir.BinaryExpr{X: x, Op: op, Y: y, Sp: expr.Sp}
+}
+
+// bindEqualityOp binds `==` and `!=` where one of the operands, of types x and y,
+// may be `nil` or is a tuple. An optional may be compared with `nil`, or with a value
+// of its element type, and a tuple with a tuple of the same type, if that is a type
+// whose values can be compared.
+func (b *binder) bindEqualityOp(kind ast.BinOpKind, x, y *bir.Ty) (bir.BinOp, bool) {
+	if kind != ast.Eq && kind != ast.Ne {
+		return bir.BinOp{}, false
+	}
+
+	if !isStructural(x) && !isStructural(y) {
+		return bir.BinOp{}, false
+	}
+
+	if !x.Accepts(y) && !y.Accepts(x) {
+		return bir.BinOp{}, false
+	}
+
+	if !x.IsNil() && !y.IsNil() && (!isComparable(x) || !isComparable(y)) {
+		return bir.BinOp{}, false
+	}
+
+	opKind := bir.Eq
+	if kind == ast.Ne {
+		opKind = bir.Ne
+	}
+	return bir.BinOp{Kind: opKind, Ty: bir.BasicTys[bir.TyBool]}, true
+}
+
+// binOpError reports that binary operator op cannot be applied to
+// operands of types x and y.
+func (b *binder) binOpError(op ast.BinOp, x, y *bir.Ty) {
+	sp := op.Sp
+	if isIntFloatMix(x, y) {
+		b.error(
+			sp,
+			"mismatched types `%s` and `%s` for `%s`, convert one of them with `int(x)` or `float(x)`",
+			x,
+			y,
+			op.Kind,
+		)
+		return
+	}
+
+	switch op.Kind {
+	case ast.Add:
+		b.error(sp, "cannot add `%s` to `%s`", x, y)
+	case ast.Sub:
+		b.error(sp, "cannot subtract `%s` from `%s`", y, x)
+	case ast.Mul:
+		b.error(sp, "cannot multiply `%s` by `%s`", x, y)
+	case ast.Div:
+		b.error(sp, "cannot divide `%s` by `%s`", x, y)
+	case ast.Rem:
+		b.error(
+			sp,
+			"cannot calculate the remainder of `%s` divided by `%s`",
+			x,
+			y,
+		)
+	case ast.BitAnd, ast.BitOr, ast.BitXor, ast.Shl, ast.Shr:
+		b.error(sp, "cannot apply `%s` to `%s` and `%s`", op.Kind, x, y)
+	case ast.Gt, ast.Lt, ast.Ge, ast.Le, ast.Eq, ast.Ne:
+		b.error(sp, "cannot compare `%s` with `%s`", x, y)
+	case ast.And, ast.Or:
+		b.error(
+			sp,
+			"expected `bool` operands for `%s`, but got `%s` and `%s`",
+			op.Kind,
+			x,
+			y,
+		)
+	default:
+		panic("unreachable")
+	}
+}
+
+// isStructural reports whether values of type ty are compared by
+// `bindEqualityOp` rather than by a basic binary operator.
+func isStructural(ty *bir.Ty) bool {
+	return ty.IsOptional() || ty.IsNil() || ty.IsTuple()
+}
+
+// isComparable reports whether values of type ty can be compared with `==`.
+func isComparable(ty *bir.Ty) bool {
+	switch ty.Kind {
+	case bir.TyNil:
+		return true
+	case bir.TyOptional:
+		return isComparable(ty.Elem)
+	case bir.TyTuple:
+		for _, elem := range ty.Elems {
+			if !isComparable(elem) {
+				return false
+			}
+		}
+		return true
+	default:
+		_, ok := bir.BindBinOp(ast.Eq, ty.Kind, ty.Kind)
+		return ok
+	}
 }
 
 func (b *binder) bindLetExpr(expr *ast.LetExpr) bir.Expr {
+	if expr.Pat != nil {
+		return b.bindDestructuringLet(expr)
+	}
+
+	// The variable is declared even if the let fails, with the annotated type
+	// or else the error type, so that its uses are not reported as well.
 	var ty *bir.Ty
 	init := b.bindExpr(expr.Init)
 	ty = b.lookupTy(expr.Decl.Ty)
 	if !ty.IsInfer() {
-		if ty.IsErr() {
-			b.error(expr.Decl.Ty.Sp, "cannot find type `%s` in this scope", expr.Decl.Ty)
-			return &bir.ErrExpr{}
-		}
-
-		if !ty.Equal(init.Type()) {
+		if !ty.IsErr() && !isErr(init) && !ty.Accepts(init.Type()) {
 			b.error(expr.Init.Span(), "expected `%s`, but got `%s`", ty, init.Type())
-			return &bir.ErrExpr{}
+			init = &bir.ErrExpr{}
 		}
 	} else {
 		ty = init.Type()
+		if ty.IsNil() {
+			b.error(
+				expr.Init.Span(),
+				"cannot infer the type of `%s` from `nil`, add a type annotation, e.g., `%s: int?`",
+				expr.Decl.Ident.Name,
+				expr.Decl.Ident.Name,
+			)
+			ty = bir.BasicTys[bir.TyErr]
+		} else if inferred(ty) {
+			b.error(
+				expr.Init.Span(),
+				"cannot infer the type of `%s` from `%s`, add a type annotation",
+				expr.Decl.Ident.Name,
+				ty,
+			)
+			ty = bir.BasicTys[bir.TyErr]
+		}
 	}
 
 	decl := &bir.VarDecl{Ident: (*ir.Ident)(expr.Decl.Ident), Ty: ty}
-	le := &bir.LetExpr{Decl: decl, Init: init}
 	b.scope.Insert(expr.Decl.Ident.Name, decl)
-	return le
+	if ty.IsErr() || isErr(init) {
+		return &bir.ErrExpr{}
+	}
+	return &bir.LetExpr{Decl: decl, Init: init}
+}
+
+// bindDestructuringLet binds `let (pats) [: ty] = init`. The pattern must match
+// every value of the type of init, since there is nothing to fall back on.
+func (b *binder) bindDestructuringLet(expr *ast.LetExpr) bir.Expr {
+	init := b.bindExpr(expr.Init)
+	ty := b.lookupTy(expr.Decl.Ty)
+	switch {
+	case ty.IsErr():
+	case ty.IsInfer():
+		if isErr(init) {
+			ty = bir.BasicTys[bir.TyErr]
+		} else if ty = init.Type(); inferred(ty) {
+			b.error(expr.Init.Span(), "cannot infer the type of `%s`, add a type annotation", ty)
+			ty = bir.BasicTys[bir.TyErr]
+		}
+	case !isErr(init) && !ty.Accepts(init.Type()):
+		b.error(expr.Init.Span(), "expected `%s`, but got `%s`", ty, init.Type())
+		init = &bir.ErrExpr{}
+	}
+
+	if ty.IsErr() {
+		b.declareErr(expr.Pat)
+		return &bir.ErrExpr{}
+	}
+
+	pat := b.bindPat(expr.Pat, ty, map[string]bool{})
+	if pat == nil {
+		b.declareErr(expr.Pat)
+		return &bir.ErrExpr{}
+	}
+	if !b.checkIrrefutable(expr.Pat, pat, ty) || isErr(init) {
+		return &bir.ErrExpr{}
+	}
+
+	return &bir.LetExpr{Pat: pat, Init: init}
+}
+
+// declareErr declares the variables that pattern pat binds with the error type,
+// after a let with pat has failed.
+func (b *binder) declareErr(pat ast.Pat) {
+	switch pat := pat.(type) {
+	case *ast.BindPat:
+		decl := &bir.VarDecl{Ident: (*ir.Ident)(pat.Ident), Ty: bir.BasicTys[bir.TyErr]}
+		b.scope.Insert(pat.Ident.Name, decl)
+	case *ast.ClassPat:
+		for _, f := range pat.Fields {
+			b.declareErr(f.Pat)
+		}
+	case *ast.TuplePat:
+		for _, sub := range pat.Pats {
+			b.declareErr(sub)
+		}
+	case *ast.VariantPat:
+		for _, sub := range pat.Pats {
+			b.declareErr(sub)
+		}
+	}
 }
 
 func (b *binder) bindAssignExpr(expr *ast.AssignExpr) bir.Expr {
 	x := b.bindExpr(expr.X)
 	y := b.bindExpr(expr.Y)
-	switch x := x.(type) {
-	case *bir.VarDecl:
-		if d, ok := b.scope.Get(x.Ident.Name); ok {
-			switch d := d.(type) {
-			case *bir.VarDecl:
-				if d.Type() != y.Type() {
-					b.error(expr.Y.Span(), "expected `%s`, but got `%s`", d.Type(), y.Type())
-					return &bir.ErrExpr{}
-				}
-			default:
-				b.error(expr.X.Span(), "can only assign to identifiers for now")
-				return &bir.ErrExpr{}
-			}
+	if isErr(x) || isErr(y) {
+		return &bir.ErrExpr{}
+	}
+
+	if !isPlace(x) {
+		b.error(expr.X.Span(), "cannot assign to this expression")
+		return &bir.ErrExpr{}
+	}
+
+	// A narrowed variable can be assigned any value of its declared type,
+	// after which it may no longer be known not to be `nil`.
+	ty := x.Type()
+	if n, ok := x.(*bir.Narrowed); ok {
+		x, ty = n.Decl, n.Decl.Ty
+		if !n.Ty.Accepts(y.Type()) {
+			b.scope.Widen(n.Decl)
 		}
+	}
 
-		return &bir.AssignExpr{X: x, Y: y}
-	default:
-		b.error(expr.X.Span(), "can only assign to identifiers for now")
+	if !ty.Accepts(y.Type()) {
+		b.error(expr.Y.Span(), "expected `%s`, but got `%s`", ty, y.Type())
 		return &bir.ErrExpr{}
 	}
+
+	return &bir.AssignExpr{X: x, Y: y}
+}
+
+func (b *binder) bindCompoundAssignExpr(expr *ast.CompoundAssignExpr) bir.Expr {
+	x := b.bindExpr(expr.X)
+	y := b.bindExpr(expr.Y)
+	if isErr(x) || isErr(y) {
+		return &bir.ErrExpr{}
+	}
+
+	if !isPlace(x) {
+		b.error(expr.X.Span(), "cannot assign to this expression")
+		return &bir.ErrExpr{}
+	}
+
+	op, ok := bir.BindBinOp(expr.Op.Kind, x.Type().Kind, y.Type().Kind)
+	if !ok {
+		b.binOpError(expr.Op, x.Type(), y.Type())
+		return &bir.ErrExpr{}
+	}
+
+	return &bir.CompoundAssignExpr{X: x, Op: op, Y: y, Sp: expr.Sp}
+}
+
+// bindFnExpr binds closure expr in a scope of its own. Loops and the return type
+// of the enclosing function do not extend into the body of the closure.
+func (b *binder) bindFnExpr(expr *ast.FnExpr) bir.Expr {
+	fn := &bir.FnExpr{Out: b.lookupTy(expr.Out)}
+	if fn.Out.IsErr() {
+		return &bir.ErrExpr{}
+	}
+
+	prevScope, prevLoops, prevOut, prevClass := b.scope, b.loops, b.out, b.class
+	b.class = nil
+	fn.In = b.bindParams(expr.In)
+	b.class = prevClass
+	if len(fn.In) != len(expr.In) {
+		return &bir.ErrExpr{}
+	}
+
+	b.scope = WithClosure(b.scope, fn)
+	for _, param := range fn.In {
+		b.scope.Insert(param.Ident.Name, param)
+	}
+	b.loops, b.out = nil, fn.Out
+	fn.Body = b.bindExpr(expr.Body)
+	b.scope, b.loops, b.out = prevScope, prevLoops, prevOut
+
+	if isErr(fn.Body) {
+		return fn.Body
+	}
+
+	// The value of the body is discarded if the closure returns unit.
+	bodyTy := fn.Body.Type()
+	if !fn.Out.IsUnit() && !bodyTy.IsErr() && !diverges(fn.Body) && !fn.Out.Accepts(bodyTy) {
+		b.error(expr.Out.Sp, "expected this function to return `%s`, but got `%s`", fn.Out, bodyTy)
+		fn.Body = &bir.ErrExpr{}
+	}
+	return fn
 }
 
 func (b *binder) bindIfExpr(expr *ast.IfExpr) bir.Expr {
@@ -290,20 +758,272 @@ func (b *binder) bindIfExpr(expr *ast.IfExpr) bir.Expr {
 		return &bir.ErrExpr{}
 	}
 
+	prev := b.scope
+	b.scope = WithOuter(prev)
+	b.narrow(b.narrowings(expr.Cond, true))
 	then := b.bindExpr(expr.Then)
+	b.scope = prev
+
+	ty := then.Type()
 	var els bir.Expr
 	if expr.Else != nil {
+		b.scope = WithOuter(prev)
+		b.narrow(b.narrowings(expr.Cond, false))
 		els = b.bindExpr(expr.Else)
-		if then.Type() != els.Type() {
+		b.scope = prev
+
+		// A branch that diverges produces no value, so the
+		// other one alone decides the type.
+		var ok bool
+		if diverges(then) {
+			ty = els.Type()
+		} else if diverges(els) {
+			ty = then.Type()
+		} else if ty, ok = join(then.Type(), els.Type()); !ok {
 			b.error(
 				expr.Span(),
 				"`if` and else have incompatible types, expected `%s`, but got `%s`",
 				then.Type(),
 				els.Type(),
 			)
+			ty = then.Type()
 		}
 	}
-	return &bir.IfExpr{Cond: cond, Then: then, Else: els}
+	return &bir.IfExpr{Cond: cond, Then: then, Else: els, Ty: ty}
+}
+
+func (b *binder) bindMatchExpr(expr *ast.MatchExpr) bir.Expr {
+	x := b.bindExpr(expr.X)
+	if isErr(x) {
+		return x
+	}
+
+	match := &bir.MatchExpr{X: x, Ty: bir.BasicTys[bir.TyUnit]}
+	hasError := false
+	for i, aArm := range expr.Arms {
+		prev := b.scope
+		b.scope = WithOuter(b.scope)
+		arm := &bir.MatchArm{Pat: b.bindPat(aArm.Pat, x.Type(), map[string]bool{})}
+		if arm.Pat == nil {
+			hasError = true
+		}
+
+		if aArm.Guard != nil {
+			arm.Guard = b.bindExpr(aArm.Guard)
+			if !arm.Guard.Type().IsBool() {
+				b.error(aArm.Guard.Span(), "expected `bool`, but got `%s`", arm.Guard.Type())
+				hasError = true
+			}
+		}
+
+		arm.Body = b.bindExpr(aArm.Body)
+		b.scope = prev
+
+		if i == 0 {
+			match.Ty = arm.Body.Type()
+		} else if ty, ok := join(match.Ty, arm.Body.Type()); ok {
+			match.Ty = ty
+		} else {
+			b.error(
+				aArm.Body.Span(),
+				"`match` arms have incompatible types, expected `%s`, but got `%s`",
+				match.Ty,
+				arm.Body.Type(),
+			)
+			hasError = true
+		}
+		match.Arms = append(match.Arms, arm)
+	}
+
+	if hasError {
+		return &bir.ErrExpr{}
+	}
+
+	b.checkMatch(expr, match)
+	return match
+}
+
+// bindPat binds pattern aPat against a value of type ty, and inserts its bindings
+// into the current scope. The names bound so far in the pattern are tracked in seen.
+// Returns nil if the pattern has an error.
+func (b *binder) bindPat(aPat ast.Pat, ty *bir.Ty, seen map[string]bool) bir.Pat {
+	switch aPat := aPat.(type) {
+	case *ast.WildPat:
+		return &bir.WildPat{}
+	case *ast.BindPat:
+		name := aPat.Ident.Name
+		if seen[name] {
+			b.error(aPat.Ident.Sp, "identifier `%s` is bound more than once in the same pattern", name)
+			return nil
+		}
+		seen[name] = true
+
+		decl := &bir.VarDecl{Ident: (*ir.Ident)(aPat.Ident), Ty: ty}
+		b.scope.Insert(name, decl)
+		return &bir.BindPat{Decl: decl}
+	case *ast.LitPat:
+		x := b.bindExpr(aPat.X)
+		if isErr(x) {
+			return nil
+		}
+		if !ty.Equal(x.Type()) {
+			b.error(aPat.Sp, "expected `%s`, but got `%s`", ty, x.Type())
+			return nil
+		}
+		return &bir.LitPat{X: x}
+	case *ast.RangePat:
+		return b.bindRangePat(aPat, ty)
+	case *ast.ClassPat:
+		return b.bindClassPat(aPat, ty, seen)
+	case *ast.TuplePat:
+		return b.bindTuplePat(aPat, ty, seen)
+	case *ast.VariantPat:
+		return b.bindVariantPat(aPat, ty, seen)
+	}
+	panic("unreachable")
+}
+
+func (b *binder) bindRangePat(aPat *ast.RangePat, ty *bir.Ty) bir.Pat {
+	lo, okLo := b.bindExpr(aPat.Lo).(*bir.IntegerLiteral)
+	hi, okHi := b.bindExpr(aPat.Hi).(*bir.IntegerLiteral)
+	if !okLo || !okHi {
+		return nil
+	}
+
+	if !ty.IsInt() {
+		b.error(aPat.Sp, "expected `%s`, but got `int`", ty)
+		return nil
+	}
+
+	if lo.V > hi.V || (!aPat.Inclusive && lo.V == hi.V) {
+		op := ast.Range
+		if aPat.Inclusive {
+			op = ast.RangeInclusive
+		}
+		b.error(aPat.Sp, "range pattern `%d%s%d` is empty", lo.V, op, hi.V)
+		return nil
+	}
+	return &bir.RangePat{Lo: lo.V, Hi: hi.V, Inclusive: aPat.Inclusive}
+}
+
+func (b *binder) bindClassPat(aPat *ast.ClassPat, ty *bir.Ty, seen map[string]bool) bir.Pat {
+	def, ok := b.scope.Get(aPat.Ident.Name)
+	if !ok {
+		b.error(aPat.Ident.Sp, "could not find a class named `%s` in this scope", aPat.Ident.Name)
+		return nil
+	}
+
+	class, ok := def.(*bir.Class)
+	if !ok {
+		b.error(aPat.Ident.Sp, "`%s` is not a class", aPat.Ident.Name)
+		return nil
+	}
+
+	if !ty.IsClass() || ty.Class.Name != class.Decl.Ident.Name {
+		b.error(aPat.Sp, "expected `%s`, but got `%s`", ty, aPat.Ident.Name)
+		return nil
+	}
+
+	subst := classSubst(class, ty)
+	pat := &bir.ClassPat{Class: class}
+	bound := make(map[string]bool, len(aPat.Fields))
+	for _, aField := range aPat.Fields {
+		name := aField.Ident.Name
+		field := class.Field(name)
+		if field == nil {
+			b.error(aField.Ident.Sp, "class `%s` has no field named `%s`", aPat.Ident.Name, name)
+			return nil
+		}
+
+		if bound[name] {
+			b.error(aField.Ident.Sp, "field `%s` is bound more than once in the same pattern", name)
+			return nil
+		}
+		bound[name] = true
+
+		fieldPat := b.bindPat(aField.Pat, field.Ty.Subst(subst), seen)
+		if fieldPat == nil {
+			return nil
+		}
+		pat.Fields = append(pat.Fields, &bir.PatField{Ident: field.Ident, Pat: fieldPat})
+	}
+	return pat
+}
+
+func (b *binder) bindTuplePat(aPat *ast.TuplePat, ty *bir.Ty, seen map[string]bool) bir.Pat {
+	if !ty.IsTuple() {
+		b.error(aPat.Sp, "expected `%s`, but got a tuple", ty)
+		return nil
+	}
+	if len(aPat.Pats) != len(ty.Elems) {
+		b.error(
+			aPat.Sp,
+			"expected a tuple with %d element(s), but the pattern has %d",
+			len(ty.Elems),
+			len(aPat.Pats),
+		)
+		return nil
+	}
+
+	pat := &bir.TuplePat{}
+	for i, aSub := range aPat.Pats {
+		sub := b.bindPat(aSub, ty.Elems[i], seen)
+		if sub == nil {
+			return nil
+		}
+		pat.Pats = append(pat.Pats, sub)
+	}
+	return pat
+}
+
+func (b *binder) bindVariantPat(aPat *ast.VariantPat, ty *bir.Ty, seen map[string]bool) bir.Pat {
+	def, ok := b.scope.Get(aPat.Enum.Name)
+	if !ok {
+		b.error(aPat.Enum.Sp, "could not find an enum named `%s` in this scope", aPat.Enum.Name)
+		return nil
+	}
+
+	enum, ok := def.(*bir.Enum)
+	if !ok {
+		b.error(aPat.Enum.Sp, "`%s` is not an enum", aPat.Enum.Name)
+		return nil
+	}
+
+	variant := enum.Variant(aPat.Variant.Name)
+	if variant == nil {
+		b.error(
+			aPat.Variant.Sp,
+			"no variant named `%s` in enum `%s`",
+			aPat.Variant.Name,
+			enum.Decl.Ident.Name,
+		)
+		return nil
+	}
+
+	if !ty.Equal(enum.Type()) {
+		b.error(aPat.Sp, "expected `%s`, but got `%s`", ty, enum.Type())
+		return nil
+	}
+
+	if len(aPat.Pats) != len(variant.Tys) {
+		b.error(
+			aPat.Sp,
+			"this variant has %d field(s), but the pattern has %d",
+			len(variant.Tys),
+			len(aPat.Pats),
+		)
+		return nil
+	}
+
+	pat := &bir.VariantPat{Enum: enum, Variant: variant}
+	for i, aSub := range aPat.Pats {
+		sub := b.bindPat(aSub, variant.Tys[i], seen)
+		if sub == nil {
+			return nil
+		}
+		pat.Pats = append(pat.Pats, sub)
+	}
+	return pat
 }
 
 func (b *binder) bindBlockExpr(expr *ast.BlockExpr) bir.Expr {
@@ -311,7 +1031,16 @@ func (b *binder) bindBlockExpr(expr *ast.BlockExpr) bir.Expr {
 	prev := b.scope
 	b.scope = WithOuter(b.scope)
 	for _, e := range expr.Exprs {
-		exprs = append(exprs, b.bindExpr(e))
+		x := b.bindExpr(e)
+		exprs = append(exprs, x)
+
+		// After e.g. `if x == nil { return }`, `x` is known not to be `nil`
+		// for the rest of the block.
+		if aIf, ok := e.(*ast.IfExpr); ok && aIf.Else == nil {
+			if x, ok := x.(*bir.IfExpr); ok && diverges(x.Then) {
+				b.narrow(b.narrowings(aIf.Cond, false))
+			}
+		}
 	}
 	b.scope = prev
 	return &bir.BlockExpr{Exprs: exprs}
@@ -319,42 +1048,101 @@ func (b *binder) bindBlockExpr(expr *ast.BlockExpr) bir.Expr {
 
 func (b *binder) bindCallExpr(expr *ast.CallExpr) bir.Expr {
 	fn := b.bindExpr(expr.Fn)
+	if isErr(fn) {
+		return fn
+	}
+
+	var args []bir.Expr
+	for _, arg := range expr.Args {
+		args = append(args, b.bindExpr(arg))
+	}
+
+	if intr, ok := fn.(bir.Intrinsic); ok {
+		return b.bindIntrinsicCall(expr, intr, args)
+	}
+
+	ty := fn.Type()
+	if !ty.IsFn() {
+		b.error(expr.Fn.Span(), "expected a function, but got `%s`", ty)
+		return &bir.ErrExpr{}
+	}
+
+	if len(ty.Params) != len(args) {
+		kind := "function"
+		if _, ok := fn.(*bir.MethodExpr); ok {
+			kind = "method"
+		}
+		b.error(
+			expr.Fn.Span(),
+			"this %s expects %d argument(s), but %d argument(s) were supplied",
+			kind,
+			len(ty.Params),
+			len(args),
+		)
+		return &bir.ErrExpr{}
+	}
+
+	// The type parameters of generic functions and methods are inferred from the arguments.
+	var generics []*bir.Ty
 	switch fn := fn.(type) {
 	case *bir.Fn:
-		if len(fn.Decl.In) != len(expr.Args) {
+		generics = fn.TyParams
+	case *bir.MethodExpr:
+		generics = fn.Fn.TyParams
+	}
+
+	subst := newSubst(generics)
+	ok := true
+	for i, arg := range args {
+		if isErr(arg) {
+			ok = false
+		} else if !unify(ty.Params[i], arg.Type(), subst) {
+			b.mismatch(expr.Args[i].Span(), ty.Params[i], arg.Type(), generics, subst)
+			ok = false
+		}
+	}
+	if !ok || !b.resolve(generics, ty.Params, subst, expr.Fn.Span()) {
+		return &bir.ErrExpr{}
+	}
+
+	return &bir.CallExpr{Fn: fn, Args: args, Ty: ty.Out.Subst(subst)}
+}
+
+func (b *binder) bindIntrinsicCall(expr *ast.CallExpr, intr bir.Intrinsic, args []bir.Expr) bir.Expr {
+	switch (ir.Intrinsic)(intr) {
+	case ir.IntrPrintln, ir.IntrInt, ir.IntrFloat, ir.IntrLen:
+		if len(args) != 1 {
 			b.error(
 				expr.Fn.Span(),
-				"this functions expects %d argument(s), but %d argument(s) were supplied",
-				len(fn.In),
-				len(expr.Args),
+				"`%s` expects 1 argument, but %d argument(s) were supplied",
+				(ir.Intrinsic)(intr),
+				len(args),
 			)
 			return &bir.ErrExpr{}
 		}
-	case bir.Intrinsic:
-		switch (ir.Intrinsic)(fn) {
-		case ir.IntrPrintln:
-			if len(expr.Args) != 1 {
-				b.error(
-					expr.Fn.Span(),
-					"`println` expects 1 argument, but %d argument(s) were supplied",
-					len(expr.Args),
-				)
-				return &bir.ErrExpr{}
-			}
-		default:
-			panic("unreachable")
-		}
 	default:
-		b.error(expr.Fn.Span(), "expected a function")
-		return &bir.ErrExpr{}
+		panic("unreachable")
 	}
-	var args []bir.Expr
-	if expr.Args != nil {
-		for _, arg := range expr.Args {
-			args = append(args, b.bindExpr(arg))
+
+	if isErr(args[0]) {
+		return args[0]
+	}
+
+	switch (ir.Intrinsic)(intr) {
+	case ir.IntrInt, ir.IntrFloat:
+		ty := args[0].Type()
+		if !ty.IsInt() && !ty.IsFloat() {
+			b.error(expr.Args[0].Span(), "cannot convert `%s` to `%s`", ty, intr.Type())
+			return &bir.ErrExpr{}
+		}
+	case ir.IntrLen:
+		ty := args[0].Type()
+		if !ty.IsArray() && !ty.IsString() && !ty.IsMap() {
+			b.error(expr.Args[0].Span(), "cannot take the length of `%s`", ty)
+			return &bir.ErrExpr{}
 		}
 	}
-	return &bir.CallExpr{Fn: fn, Args: args}
+	return &bir.CallExpr{Fn: intr, Args: args, Ty: intr.Type()}
 }
 
 func (b *binder) bindClassExpr(expr *ast.ClassExpr) bir.Expr {
@@ -370,22 +1158,19 @@ func (b *binder) bindClassExpr(expr *ast.ClassExpr) bir.Expr {
 		return &bir.ErrExpr{}
 	}
 
+	// The type parameters of a generic class are inferred from the fields.
+	subst := newSubst(c.TyParams)
 	exprFields := b.bindExprFields(expr.Fields)
 	hasError := false
 	for _, field := range c.Fields {
 		found := false
-		for _, exprField := range exprFields {
+		for j, exprField := range exprFields {
 			if field.Ident.Name != exprField.Ident.Name {
 				continue
 			}
 
-			if !field.Ty.Equal(exprField.Expr.Type()) {
-				b.error(
-					field.Ident.Sp,
-					"expected `%s`, but got `%s`",
-					field.Ty,
-					exprField.Expr.Type(),
-				)
+			if !unify(field.Ty, exprField.Expr.Type(), subst) {
+				b.mismatch(expr.Fields[j].Expr.Span(), field.Ty, exprField.Expr.Type(), c.TyParams, subst)
 				hasError = true
 			}
 			found = true
@@ -401,7 +1186,19 @@ func (b *binder) bindClassExpr(expr *ast.ClassExpr) bir.Expr {
 		return &bir.ErrExpr{}
 	}
 
-	return &bir.ClassExpr{Ident: (*ir.Ident)(expr.Ident), Fields: exprFields}
+	fieldTys := make([]*bir.Ty, len(c.Fields))
+	for i, field := range c.Fields {
+		fieldTys[i] = field.Ty
+	}
+	if !b.resolve(c.TyParams, fieldTys, subst, expr.Ident.Sp) {
+		return &bir.ErrExpr{}
+	}
+
+	var args []*bir.Ty
+	for _, param := range c.TyParams {
+		args = append(args, subst[param])
+	}
+	return &bir.ClassExpr{Ident: (*ir.Ident)(expr.Ident), Args: args, Fields: exprFields}
 }
 
 func (b *binder) bindExprFields(aFields []*ast.ExprField) []*bir.ExprField {
@@ -419,27 +1216,50 @@ func (b *binder) bindExprField(aField *ast.ExprField) *bir.ExprField {
 
 func (b *binder) bindFieldExpr(aExpr *ast.FieldExpr) bir.Expr {
 	expr := b.bindExpr(aExpr.Expr)
-	decl := expr.(*bir.VarDecl)
+	if isErr(expr) {
+		return expr
+	}
+
+	if enum, ok := expr.(*bir.Enum); ok {
+		return b.bindVariantExpr(enum, aExpr.Ident)
+	}
+
 	var ty *bir.Ty
-	switch decl.Ty.Kind {
+	switch recvTy := expr.Type(); recvTy.Kind {
 	case bir.TyClass:
-		classExpr, _ := b.scope.Get(decl.Ty.Class.Name)
-		class := classExpr.(*bir.Class)
-		found := false
-		for _, f := range class.Fields {
-			if f.Ident.Name == aExpr.Ident.Name {
-				ty = f.Ty
-				found = true
-			}
-		}
-		if !found {
+		class := b.classOf(recvTy)
+		subst := classSubst(class, recvTy)
+		if field := class.Field(aExpr.Ident.Name); field != nil {
+			ty = field.Ty.Subst(subst)
+		} else if method := class.Method(aExpr.Ident.Name); method != nil {
+			// The receiver is already bound to `self`.
+			fnTy := method.Type().Subst(subst)
+			ty := bir.NewFn(fnTy.Params[1:], fnTy.Out)
+			return &bir.MethodExpr{Recv: expr, Fn: method, Ty: ty}
+		} else {
 			b.error(
 				aExpr.Ident.Sp,
-				"could not find field `%s` in class `%s`",
+				"could not find field or method `%s` in class `%s`",
 				aExpr.Ident.Name,
 				class.Decl.Ident.Name,
 			)
+			return &bir.ErrExpr{}
+		}
+	case bir.TyTuple:
+		i, err := strconv.Atoi(aExpr.Ident.Name)
+		if err != nil || i >= len(recvTy.Elems) {
+			b.error(aExpr.Ident.Sp, "no field `%s` on tuple `%s`", aExpr.Ident.Name, recvTy)
+			return &bir.ErrExpr{}
 		}
+		return &bir.TupleIndexExpr{X: expr, I: i}
+	case bir.TyOptional:
+		b.error(
+			aExpr.Expr.Span(),
+			"cannot access `%s` on `%s`, which may be `nil`, compare it with `nil` first",
+			aExpr.Ident.Name,
+			expr.Type(),
+		)
+		return &bir.ErrExpr{}
 	default:
 		b.error(aExpr.Expr.Span(), "expected a class, but got `%s`", expr.Type())
 		return &bir.ErrExpr{}
@@ -447,6 +1267,34 @@ func (b *binder) bindFieldExpr(aExpr *ast.FieldExpr) bir.Expr {
 	return &bir.FieldExpr{Ident: (*ir.Ident)(aExpr.Ident), Expr: expr, Ty: ty}
 }
 
+// bindVariantExpr binds `enum.ident`. A variant with a payload is bound to
+// a function that takes the payload and constructs the variant.
+func (b *binder) bindVariantExpr(enum *bir.Enum, ident *ast.Ident) bir.Expr {
+	variant := enum.Variant(ident.Name)
+	if variant == nil {
+		b.error(ident.Sp, "no variant named `%s` in enum `%s`", ident.Name, enum.Decl.Ident.Name)
+		return &bir.ErrExpr{}
+	}
+
+	if len(variant.Tys) == 0 {
+		return &bir.VariantExpr{Enum: enum, Variant: variant}
+	}
+
+	ctor := &bir.FnExpr{Out: enum.Type()}
+	var args []bir.Expr
+	for i, ty := range variant.Tys {
+		if ty.IsErr() {
+			return &bir.ErrExpr{}
+		}
+		name := &ir.Ident{Name: strconv.Itoa(i), Sp: variant.Ident.Sp}
+		param := &bir.VarDecl{Ident: name, Ty: ty}
+		ctor.In = append(ctor.In, param)
+		args = append(args, param)
+	}
+	ctor.Body = &bir.VariantExpr{Enum: enum, Variant: variant, Args: args}
+	return ctor
+}
+
 func (b *binder) bindArrayExpr(expr *ast.ArrayExpr) bir.Expr {
 	if len(expr.Exprs) == 0 {
 		return &bir.ArrayExpr{Exprs: []bir.Expr{}}
@@ -460,7 +1308,7 @@ func (b *binder) bindArrayExpr(expr *ast.ArrayExpr) bir.Expr {
 	expectedTy := exprs[0].Type()
 	for i := 1; i < len(exprs); i++ {
 		actualTy := exprs[i].Type()
-		if expectedTy != actualTy {
+		if !expectedTy.Equal(actualTy) {
 			b.error(expr.Exprs[i].Span(), "expected `%s`, but got `%s`", expectedTy, actualTy)
 			return &bir.ErrExpr{}
 		}
@@ -469,10 +1317,62 @@ func (b *binder) bindArrayExpr(expr *ast.ArrayExpr) bir.Expr {
 	return &bir.ArrayExpr{Exprs: exprs}
 }
 
+func (b *binder) bindMapExpr(expr *ast.MapExpr) bir.Expr {
+	if len(expr.Entries) == 0 {
+		return &bir.MapExpr{Entries: []*bir.MapEntry{}}
+	}
+
+	var entries []*bir.MapEntry
+	for _, entry := range expr.Entries {
+		key := b.bindExpr(entry.Key)
+		val := b.bindExpr(entry.Val)
+		if isErr(key) || isErr(val) {
+			return &bir.ErrExpr{}
+		}
+		entries = append(entries, &bir.MapEntry{Key: key, Val: val})
+	}
+
+	keyTy, valTy := entries[0].Key.Type(), entries[0].Val.Type()
+	if !isHashable(keyTy) {
+		b.error(expr.Entries[0].Key.Span(), "`%s` cannot be used as a map key", keyTy)
+		return &bir.ErrExpr{}
+	}
+	for i := 1; i < len(entries); i++ {
+		if actualTy := entries[i].Key.Type(); !keyTy.Equal(actualTy) {
+			b.error(expr.Entries[i].Key.Span(), "expected `%s`, but got `%s`", keyTy, actualTy)
+			return &bir.ErrExpr{}
+		}
+		if actualTy := entries[i].Val.Type(); !valTy.Equal(actualTy) {
+			b.error(expr.Entries[i].Val.Span(), "expected `%s`, but got `%s`", valTy, actualTy)
+			return &bir.ErrExpr{}
+		}
+	}
+
+	return &bir.MapExpr{Entries: entries}
+}
+
+func (b *binder) bindTupleExpr(expr *ast.TupleExpr) bir.Expr {
+	var exprs []bir.Expr
+	for _, expr := range expr.Exprs {
+		x := b.bindExpr(expr)
+		if isErr(x) {
+			return x
+		}
+		exprs = append(exprs, x)
+	}
+	return &bir.TupleExpr{Exprs: exprs}
+}
+
 func (b *binder) bindIndexExpr(expr *ast.IndexExpr) bir.Expr {
 	arr := b.bindExpr(expr.Arr)
+	if isErr(arr) {
+		return arr
+	}
+	if arr.Type().IsMap() {
+		return b.bindMapIndexExpr(expr, arr)
+	}
 	if !arr.Type().IsArray() {
-		b.error(expr.Arr.Span(), "expected an array, but got `%s`", arr.Type())
+		b.error(expr.Arr.Span(), "expected an array or a map, but got `%s`", arr.Type())
 		return &bir.ErrExpr{}
 	}
 
@@ -482,28 +1382,156 @@ func (b *binder) bindIndexExpr(expr *ast.IndexExpr) bir.Expr {
 		return &bir.ErrExpr{}
 	}
 
-	return &bir.IndexExpr{Arr: arr, I: i}
+	return &bir.IndexExpr{Arr: arr, I: i, Sp: expr.Sp}
+}
+
+// bindMapIndexExpr binds `m[key]`, where m is already bound.
+func (b *binder) bindMapIndexExpr(expr *ast.IndexExpr, m bir.Expr) bir.Expr {
+	key := b.bindExpr(expr.I)
+	if isErr(key) {
+		return key
+	}
+	if keyTy := m.Type().Key; !keyTy.Accepts(key.Type()) {
+		b.error(expr.I.Span(), "expected `%s`, but got `%s`", keyTy, key.Type())
+		return &bir.ErrExpr{}
+	}
+	return &bir.MapIndexExpr{Map: m, Key: key, Sp: expr.Sp}
+}
+
+func (b *binder) bindRangeExpr(expr *ast.RangeExpr) bir.Expr {
+	lo := b.bindExpr(expr.Lo)
+	hi := b.bindExpr(expr.Hi)
+	if isErr(lo) || isErr(hi) {
+		return &bir.ErrExpr{}
+	}
+
+	if !lo.Type().IsInt() {
+		b.error(expr.Lo.Span(), "expected `int`, but got `%s`", lo.Type())
+		return &bir.ErrExpr{}
+	}
+
+	if !hi.Type().IsInt() {
+		b.error(expr.Hi.Span(), "expected `int`, but got `%s`", hi.Type())
+		return &bir.ErrExpr{}
+	}
+
+	return &bir.RangeExpr{Lo: lo, Hi: hi, Inclusive: expr.Inclusive}
 }
 
 func (b *binder) bindForExpr(expr *ast.ForExpr) bir.Expr {
-	var body bir.Expr = &bir.ErrExpr{}
-	b.loopLevel += 1
-	body = b.bindExpr(expr.Body)
-	return &bir.ForExpr{Body: body}
+	b.widenAssigned(expr.Cond, expr.Body)
+
+	var cond bir.Expr
+	if expr.Cond != nil {
+		cond = b.bindExpr(expr.Cond)
+		if !cond.Type().IsBool() {
+			b.error(expr.Cond.Span(), "expected `bool`, but got `%s`", cond.Type())
+			return &bir.ErrExpr{}
+		}
+	}
+
+	var iter bir.Expr
+	var decl, val *bir.VarDecl
+	prev := b.scope
+	if expr.Iter != nil {
+		iter = b.bindExpr(expr.Iter)
+		if isErr(iter) {
+			return &bir.ErrExpr{}
+		}
+
+		// Iterating over a map yields its keys, and its values as well
+		// if there is a second loop variable.
+		var elemTy, valTy *bir.Ty
+		switch ty := iter.Type(); ty.Kind {
+		case bir.TyArray:
+			elemTy = ty.Elem
+		case bir.TyRange:
+			elemTy = bir.BasicTys[bir.TyInt]
+		case bir.TyMap:
+			elemTy, valTy = ty.Key, ty.Elem
+		default:
+			b.error(expr.Iter.Span(), "cannot iterate over `%s`", ty)
+			return &bir.ErrExpr{}
+		}
+		if expr.Val != nil && valTy == nil {
+			b.error(expr.Val.Sp, "cannot iterate over `%s` with two loop variables, only a map can", iter.Type())
+			return &bir.ErrExpr{}
+		}
+
+		b.scope = WithOuter(b.scope)
+		decl = &bir.VarDecl{Ident: (*ir.Ident)(expr.Ident), Ty: elemTy}
+		b.scope.Insert(expr.Ident.Name, decl)
+		if expr.Val != nil {
+			val = &bir.VarDecl{Ident: (*ir.Ident)(expr.Val), Ty: valTy}
+			b.scope.Insert(expr.Val.Name, val)
+		}
+	}
+
+	if expr.Cond != nil {
+		b.scope = WithOuter(b.scope)
+		b.narrow(b.narrowings(expr.Cond, true))
+	}
+
+	loop := &bir.ForExpr{Label: (*ir.Ident)(expr.Label), Cond: cond, Var: decl, Val: val, Iter: iter}
+	b.loops = append(b.loops, loop)
+	loop.Body = b.bindExpr(expr.Body)
+	b.loops = b.loops[:len(b.loops)-1]
+	b.scope = prev
+	return loop
 }
 
 func (b *binder) bindBreakExpr(expr *ast.BreakExpr) bir.Expr {
-	if b.loopLevel == 0 {
+	if len(b.loops) == 0 {
 		b.error(expr.Sp, "cannot `break` outside a `for` loop")
 		return &bir.ErrExpr{}
 	}
 
+	loop := b.lookupLoop(expr.Label)
+	if loop == nil {
+		return &bir.ErrExpr{}
+	}
+
 	var x bir.Expr
 	if expr.X != nil {
+		// Only an unconditional loop is sure to end at a `break` that gives it its value.
+		if loop.Cond != nil || loop.Iter != nil {
+			b.error(expr.X.Span(), "cannot `break` with a value out of a `for` loop with a condition or iterator")
+			return &bir.ErrExpr{}
+		}
 		x = b.bindExpr(expr.X)
 	}
-	b.loopLevel -= 1
-	return &bir.BreakExpr{X: x}
+	return &bir.BreakExpr{X: x, Loop: loop}
+}
+
+func (b *binder) bindContinueExpr(expr *ast.ContinueExpr) bir.Expr {
+	if len(b.loops) == 0 {
+		b.error(expr.Sp, "cannot `continue` outside a `for` loop")
+		return &bir.ErrExpr{}
+	}
+
+	loop := b.lookupLoop(expr.Label)
+	if loop == nil {
+		return &bir.ErrExpr{}
+	}
+	return &bir.ContinueExpr{Loop: loop}
+}
+
+// lookupLoop returns the innermost enclosing loop with the given label,
+// or the innermost enclosing loop if label is nil.
+// If there is no loop with that label, an error is reported and nil is returned.
+func (b *binder) lookupLoop(label *ast.Ident) *bir.ForExpr {
+	if label == nil {
+		return b.loops[len(b.loops)-1]
+	}
+
+	for i := len(b.loops) - 1; i >= 0; i-- {
+		if l := b.loops[i].Label; l != nil && l.Name == label.Name {
+			return b.loops[i]
+		}
+	}
+
+	b.error(label.Sp, "use of undeclared label `%s`", label.Name)
+	return nil
 }
 
 func (b *binder) bindReturnExpr(expr *ast.ReturnExpr) bir.Expr {
@@ -512,11 +1540,16 @@ func (b *binder) bindReturnExpr(expr *ast.ReturnExpr) bir.Expr {
 		x = b.bindExpr(expr.X)
 	}
 
-	if b.fn.Out.IsUnit() && x != nil {
+	if x == nil {
+		if !b.out.IsUnit() {
+			b.error(expr.Sp, "expected this function to return `%s`, but got `()`", b.out)
+			return &bir.ErrExpr{}
+		}
+	} else if !x.Type().IsErr() && (b.out.IsUnit() || !b.out.Accepts(x.Type())) {
 		b.error(
 			expr.X.Span(),
 			"expected this function to return `%s`, but got `%s`",
-			b.fn.Out,
+			b.out,
 			x.Type(),
 		)
 		return &bir.ErrExpr{}
@@ -525,6 +1558,27 @@ func (b *binder) bindReturnExpr(expr *ast.ReturnExpr) bir.Expr {
 	return &bir.ReturnExpr{X: x}
 }
 
+// isIntFloatMix returns true if one of x and y is an `int` and the other one a `float`,
+// otherwise false.
+func isIntFloatMix(x, y *bir.Ty) bool {
+	return (x.IsInt() && y.IsFloat()) || (x.IsFloat() && y.IsInt())
+}
+
+// isPlace reports whether expr denotes a location that can be assigned to.
+func isPlace(expr bir.Expr) bool {
+	switch expr.(type) {
+	case *bir.VarDecl, *bir.Narrowed, *bir.FieldExpr, *bir.IndexExpr, *bir.MapIndexExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// isHashable reports whether values of type ty can be used as map keys.
+func isHashable(ty *bir.Ty) bool {
+	return ty.IsInt() || ty.IsString() || ty.IsBool()
+}
+
 func isErr(expr bir.Expr) bool {
 	switch expr.(type) {
 	case *bir.ErrExpr:
@@ -539,17 +1593,102 @@ func (b *binder) error(span span.Span, format string, a ...any) {
 	diagnostic.NewBuilder(msg, span).WithLabel("here").Emit(b.sess.Diags)
 }
 
+func (b *binder) warn(span span.Span, format string, a ...any) {
+	msg := fmt.Sprintf(format, a...)
+	diagnostic.NewBuilder(msg, span).WithLabel("here").AsWarning().Emit(b.sess.Diags)
+}
+
 func (b *binder) lookupTy(ty *ast.Ty) *bir.Ty {
 	switch ty.Kind {
 	case ast.TyInfer:
 		return bir.BasicTys[bir.TyInfer]
 	case ast.TyArray:
-		return bir.NewArray(lookUpBasicTy(ty.Ident))
+		elem := b.lookupTy(ty.Elem)
+		if elem.IsErr() {
+			return elem
+		}
+		return bir.NewArray(elem)
+	case ast.TyMap:
+		key := b.lookupTy(ty.Key)
+		if key.IsErr() {
+			return key
+		}
+		if !isHashable(key) {
+			b.error(ty.Key.Sp, "`%s` cannot be used as a map key", key)
+			return bir.BasicTys[bir.TyErr]
+		}
+		val := b.lookupTy(ty.Elem)
+		if val.IsErr() {
+			return val
+		}
+		return bir.NewMap(key, val)
 	case ast.TyIdent:
-		if _, ok := b.scope.Get(ty.Ident.Name); ok {
-			return bir.NewClass((*ir.Ident)(ty.Ident))
+		name := ty.Ident.Name
+		if t := b.lookupTyParam(name); t != nil {
+			return b.noTyArgs(ty, t)
 		}
-		return lookUpBasicTy(ty.Ident)
+		if basic := lookUpBasicTy(ty.Ident); !basic.IsErr() {
+			return b.noTyArgs(ty, basic)
+		}
+		if def, ok := b.scope.Get(name); ok {
+			if enum, ok := def.(*bir.Enum); ok {
+				return b.noTyArgs(ty, enum.Type())
+			}
+			if class, ok := def.(*bir.Class); ok {
+				if len(ty.Args) != len(class.TyParams) {
+					b.error(
+						ty.Sp,
+						"expected %d type argument(s) for `%s`, but got %d",
+						len(class.TyParams),
+						name,
+						len(ty.Args),
+					)
+					return bir.BasicTys[bir.TyErr]
+				}
+
+				args := make([]*bir.Ty, len(ty.Args))
+				for i, arg := range ty.Args {
+					if args[i] = b.lookupTy(arg); args[i].IsErr() {
+						return args[i]
+					}
+				}
+				return bir.NewClass((*ir.Ident)(ty.Ident), args)
+			}
+		}
+		b.error(ty.Ident.Sp, "cannot find type `%s` in this scope", name)
+		return bir.BasicTys[bir.TyErr]
+	case ast.TyOptional:
+		elem := b.lookupTy(ty.Elem)
+		if elem.IsErr() {
+			return elem
+		}
+		return bir.NewOptional(elem)
+	case ast.TyFn:
+		params := make([]*bir.Ty, len(ty.Params))
+		for i, param := range ty.Params {
+			params[i] = b.lookupTy(param)
+			if params[i].IsErr() {
+				return params[i]
+			}
+		}
+		out := b.lookupTy(ty.Out)
+		if out.IsErr() {
+			return out
+		}
+		return bir.NewFn(params, out)
+	case ast.TyTuple:
+		elems := make([]*bir.Ty, len(ty.Elems))
+		for i, elem := range ty.Elems {
+			if elems[i] = b.lookupTy(elem); elems[i].IsErr() {
+				return elems[i]
+			}
+		}
+		return bir.NewTuple(elems)
+	case ast.TySelf:
+		if b.class == nil {
+			return bir.BasicTys[bir.TyErr]
+		}
+		return b.class.Type()
 	case ast.TyUnit:
 		return bir.BasicTys[bir.TyUnit]
 	default:
@@ -557,10 +1696,30 @@ func (b *binder) lookupTy(ty *ast.Ty) *bir.Ty {
 	}
 }
 
+func (b *binder) lookupTyParam(name string) *bir.Ty {
+	for _, param := range b.tyParams {
+		if param.Name.Name == name {
+			return param
+		}
+	}
+	return nil
+}
+
+// noTyArgs returns type t, or reports an error if ty has type arguments.
+func (b *binder) noTyArgs(ty *ast.Ty, t *bir.Ty) *bir.Ty {
+	if len(ty.Args) > 0 {
+		b.error(ty.Sp, "`%s` does not take type arguments", ty.Ident.Name)
+		return bir.BasicTys[bir.TyErr]
+	}
+	return t
+}
+
 func lookUpBasicTy(ty *ast.Ident) *bir.Ty {
 	switch ty.Name {
 	case "int":
 		return bir.BasicTys[bir.TyInt]
+	case "float":
+		return bir.BasicTys[bir.TyFloat]
 	case "bool":
 		return bir.BasicTys[bir.TyBool]
 	case "string":
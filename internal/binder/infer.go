@@ -0,0 +1,208 @@
+package binder
+
+import (
+	"github.com/aadamandersson/lue/internal/ir/bir"
+	"github.com/aadamandersson/lue/internal/span"
+)
+
+// newSubst returns a substitution in which none of params is bound yet.
+func newSubst(params []*bir.Ty) map[*bir.Ty]*bir.Ty {
+	subst := make(map[*bir.Ty]*bir.Ty, len(params))
+	for _, param := range params {
+		subst[param] = nil
+	}
+	return subst
+}
+
+// classSubst returns the substitution of the type parameters of class
+// with the type arguments of ty.
+func classSubst(class *bir.Class, ty *bir.Ty) map[*bir.Ty]*bir.Ty {
+	subst := make(map[*bir.Ty]*bir.Ty, len(class.TyParams))
+	for i, param := range class.TyParams {
+		if i < len(ty.Args) {
+			subst[param] = ty.Args[i]
+		}
+	}
+	return subst
+}
+
+// unify reports whether a value of type got can be used where type want is expected.
+// Type parameters in want that are keys of subst are inferred: the first type
+// one is unified with is bound to it, and every later one must equal that type.
+// All other type parameters only unify with themselves.
+func unify(want, got *bir.Ty, subst map[*bir.Ty]*bir.Ty) bool {
+	if want.IsInfer() || got.IsInfer() || got.IsErr() {
+		return true
+	}
+
+	// An optional type also accepts `nil` and values of its element type.
+	if want.IsOptional() && !got.IsOptional() {
+		return got.IsNil() || unify(want.Elem, got, subst)
+	}
+
+	if want.IsParam() {
+		bound, inferred := subst[want]
+		if !inferred {
+			return want == got
+		}
+		if bound == nil {
+			subst[want] = got
+			return true
+		}
+		return bound.Equal(got)
+	}
+
+	if want.Kind != got.Kind {
+		return false
+	}
+
+	switch want.Kind {
+	case bir.TyArray, bir.TyOptional:
+		return unify(want.Elem, got.Elem, subst)
+	case bir.TyMap:
+		return unify(want.Key, got.Key, subst) && unify(want.Elem, got.Elem, subst)
+	case bir.TyClass:
+		if want.Class.Name != got.Class.Name || len(want.Args) != len(got.Args) {
+			return false
+		}
+		for i, arg := range want.Args {
+			if !unify(arg, got.Args[i], subst) {
+				return false
+			}
+		}
+		return true
+	case bir.TyFn:
+		if len(want.Params) != len(got.Params) {
+			return false
+		}
+		for i, param := range want.Params {
+			if !unify(param, got.Params[i], subst) {
+				return false
+			}
+		}
+		return unify(want.Out, got.Out, subst)
+	case bir.TyTuple:
+		if len(want.Elems) != len(got.Elems) {
+			return false
+		}
+		for i, elem := range want.Elems {
+			if !unify(elem, got.Elems[i], subst) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// inferredParam returns the first type parameter of params that occurs in want
+// and is already bound in subst, or nil if there is none.
+func inferredParam(want *bir.Ty, params []*bir.Ty, subst map[*bir.Ty]*bir.Ty) *bir.Ty {
+	for _, param := range params {
+		if subst[param] != nil && mentions(want, param) {
+			return param
+		}
+	}
+	return nil
+}
+
+// mismatch reports that a value of type got was given where type want is expected
+// at span sp. If want mentions a type parameter that has already been inferred from
+// an earlier argument or field, the type it was inferred to be is reported as well.
+func (b *binder) mismatch(sp span.Span, want, got *bir.Ty, params []*bir.Ty, subst map[*bir.Ty]*bir.Ty) {
+	if param := inferredParam(want, params, subst); param != nil {
+		b.error(
+			sp,
+			"expected `%s`, but got `%s`, since `%s` is inferred to be `%s`",
+			want.Subst(subst),
+			got,
+			param,
+			subst[param],
+		)
+		return
+	}
+	b.error(sp, "expected `%s`, but got `%s`", want.Subst(subst), got)
+}
+
+// resolve checks that every type parameter in params could be inferred from
+// the types in inputs, and reports the ones that could not at span sp.
+// Parameters that are only unified with inferred types, such as the
+// element type of an empty array, are bound to an inferred type.
+func (b *binder) resolve(params, inputs []*bir.Ty, subst map[*bir.Ty]*bir.Ty, sp span.Span) bool {
+	ok := true
+	for _, param := range params {
+		if !mentionsAny(inputs, param) {
+			b.error(sp, "cannot infer type parameter `%s`", param)
+			ok = false
+		} else if subst[param] == nil {
+			subst[param] = bir.BasicTys[bir.TyInfer]
+		}
+	}
+	return ok
+}
+
+// mentions reports whether type parameter param occurs in type t.
+func mentions(t, param *bir.Ty) bool {
+	switch t.Kind {
+	case bir.TyParam:
+		return t == param
+	case bir.TyArray, bir.TyOptional:
+		return mentions(t.Elem, param)
+	case bir.TyMap:
+		return mentions(t.Key, param) || mentions(t.Elem, param)
+	case bir.TyClass:
+		return mentionsAny(t.Args, param)
+	case bir.TyFn:
+		return mentionsAny(t.Params, param) || mentions(t.Out, param)
+	case bir.TyTuple:
+		return mentionsAny(t.Elems, param)
+	default:
+		return false
+	}
+}
+
+func mentionsAny(tys []*bir.Ty, param *bir.Ty) bool {
+	for _, t := range tys {
+		if mentions(t, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// inferred reports whether type t contains a type that is yet to be inferred,
+// e.g., the element type of an empty array or map literal.
+func inferred(t *bir.Ty) bool {
+	switch t.Kind {
+	case bir.TyInfer:
+		return true
+	case bir.TyArray, bir.TyOptional:
+		return inferred(t.Elem)
+	case bir.TyMap:
+		return inferred(t.Key) || inferred(t.Elem)
+	case bir.TyClass:
+		return anyInferred(t.Args)
+	case bir.TyFn:
+		return anyInferred(t.Params) || inferred(t.Out)
+	case bir.TyTuple:
+		return anyInferred(t.Elems)
+	default:
+		return false
+	}
+}
+
+func anyInferred(tys []*bir.Ty) bool {
+	for _, t := range tys {
+		if inferred(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func concatTys(a, b []*bir.Ty) []*bir.Ty {
+	tys := make([]*bir.Ty, 0, len(a)+len(b))
+	tys = append(tys, a...)
+	return append(tys, b...)
+}
@@ -0,0 +1,445 @@
+package binder
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aadamandersson/lue/internal/ir/ast"
+	"github.com/aadamandersson/lue/internal/ir/bir"
+)
+
+// Reachability of match arms and exhaustiveness of match expressions are
+// checked with the usefulness algorithm from "Warnings for pattern matching"
+// by Luc Maranget. A row of patterns is useful with respect to a matrix of
+// rows, if there is a value that it matches but none of the rows do.
+// An arm is unreachable if its pattern is not useful with respect to the
+// unguarded arms before it, and a match is exhaustive if a wildcard is not
+// useful with respect to all of its unguarded arms.
+
+// A deconstructed pattern. A pattern with a nil ctor is a wildcard,
+// which is what bindings are deconstructed to as well.
+type pat struct {
+	ctor   *ctor
+	fields []*pat // One pattern per field of ctor.
+}
+
+type ctorKind int
+
+const (
+	ctorBool ctorKind = iota
+	ctorInt           // An inclusive range of integers, a single integer if lo equals hi.
+	ctorString
+	ctorVariant
+	ctorClass
+	ctorTuple
+)
+
+// A constructor of values, e.g., `true` or `Shape.Circle`.
+type ctor struct {
+	kind    ctorKind
+	b       bool
+	lo, hi  int
+	s       string
+	enum    *bir.Enum
+	variant *bir.Variant
+	class   *bir.Class
+}
+
+// checkMatch warns about the unreachable arms of match and
+// reports an error if match is not exhaustive.
+func (b *binder) checkMatch(expr *ast.MatchExpr, match *bir.MatchExpr) {
+	tys := []*bir.Ty{match.X.Type()}
+	var rows [][]*pat
+	for i, arm := range match.Arms {
+		row := []*pat{b.lower(arm.Pat, tys[0])}
+		if _, useful := b.useful(rows, row, tys); !useful {
+			b.warn(expr.Arms[i].Pat.Span(), "unreachable pattern")
+		}
+
+		// A guard may fail, so a guarded arm does not cover anything.
+		if arm.Guard == nil {
+			rows = append(rows, row)
+		}
+	}
+
+	if witness, useful := b.useful(rows, []*pat{{}}, tys); useful {
+		b.error(expr.X.Span(), "non-exhaustive patterns: `%s` not covered", witness[0])
+	}
+}
+
+// checkIrrefutable reports an error and returns false if pattern p, which is bound
+// from aPat, does not match every value of type ty. Otherwise, returns true.
+func (b *binder) checkIrrefutable(aPat ast.Pat, p bir.Pat, ty *bir.Ty) bool {
+	rows := [][]*pat{{b.lower(p, ty)}}
+	if witness, useful := b.useful(rows, []*pat{{}}, []*bir.Ty{ty}); useful {
+		b.error(aPat.Span(), "refutable pattern in `let`: `%s` not covered", witness[0])
+		return false
+	}
+	return true
+}
+
+// lower deconstructs pattern p, which matches values of type ty.
+func (b *binder) lower(p bir.Pat, ty *bir.Ty) *pat {
+	switch p := p.(type) {
+	case *bir.WildPat, *bir.BindPat:
+		return &pat{}
+	case *bir.LitPat:
+		switch x := p.X.(type) {
+		case *bir.BooleanLiteral:
+			return &pat{ctor: &ctor{kind: ctorBool, b: x.V}}
+		case *bir.IntegerLiteral:
+			return &pat{ctor: &ctor{kind: ctorInt, lo: x.V, hi: x.V}}
+		case *bir.StringLiteral:
+			return &pat{ctor: &ctor{kind: ctorString, s: x.V}}
+		}
+	case *bir.RangePat:
+		hi := p.Hi
+		if !p.Inclusive {
+			hi--
+		}
+		return &pat{ctor: &ctor{kind: ctorInt, lo: p.Lo, hi: hi}}
+	case *bir.ClassPat:
+		c := &ctor{kind: ctorClass, class: p.Class}
+		fieldTys := b.fieldTys(c, ty)
+		fields := make([]*pat, len(p.Class.Fields))
+		for i, field := range p.Class.Fields {
+			fields[i] = &pat{}
+			for _, f := range p.Fields {
+				if f.Ident.Name == field.Ident.Name {
+					fields[i] = b.lower(f.Pat, fieldTys[i])
+				}
+			}
+		}
+		return &pat{ctor: c, fields: fields}
+	case *bir.TuplePat:
+		fields := make([]*pat, len(p.Pats))
+		for i, sub := range p.Pats {
+			fields[i] = b.lower(sub, ty.Elems[i])
+		}
+		return &pat{ctor: &ctor{kind: ctorTuple}, fields: fields}
+	case *bir.VariantPat:
+		fields := make([]*pat, len(p.Pats))
+		for i, sub := range p.Pats {
+			fields[i] = b.lower(sub, p.Variant.Tys[i])
+		}
+		return &pat{ctor: &ctor{kind: ctorVariant, enum: p.Enum, variant: p.Variant}, fields: fields}
+	}
+	panic("unreachable")
+}
+
+// useful reports whether row q is useful with respect to rows, where tys are
+// the types of the columns. If it is, a witness is returned as well, which is
+// a row of patterns matching values that q matches but none of the rows do.
+func (b *binder) useful(rows [][]*pat, q []*pat, tys []*bir.Ty) ([]*pat, bool) {
+	if len(q) == 0 {
+		return []*pat{}, len(rows) == 0
+	}
+
+	head, ty := q[0], tys[0]
+	if head.ctor != nil {
+		for _, c := range splitCtor(head.ctor, rows) {
+			fieldTys := b.fieldTys(c, ty)
+			spec := specialize(rows, c, len(fieldTys))
+			if w, ok := b.useful(spec, concatPats(head.fields, q[1:]), concatTys(fieldTys, tys[1:])); ok {
+				return rebuild(c, len(fieldTys), w), true
+			}
+		}
+		return nil, false
+	}
+
+	if ctors, complete := b.signature(rows, ty); complete {
+		for _, c := range ctors {
+			fieldTys := b.fieldTys(c, ty)
+			spec := specialize(rows, c, len(fieldTys))
+			if w, ok := b.useful(spec, concatPats(wilds(len(fieldTys)), q[1:]), concatTys(fieldTys, tys[1:])); ok {
+				return rebuild(c, len(fieldTys), w), true
+			}
+		}
+		return nil, false
+	}
+
+	w, ok := b.useful(defaultRows(rows), q[1:], tys[1:])
+	if !ok {
+		return nil, false
+	}
+	return concatPats([]*pat{b.missing(rows, ty)}, w), true
+}
+
+// signature returns all constructors of type ty and a boolean true, if every one
+// of them is covered by a constructor in the first column of rows.
+// Otherwise, returns nil and a boolean false.
+// Classes and tuples have a single constructor, which is always covered, while strings,
+// floats and the like have too many constructors to ever be covered.
+func (b *binder) signature(rows [][]*pat, ty *bir.Ty) ([]*ctor, bool) {
+	var ctors []*ctor
+	switch ty.Kind {
+	case bir.TyBool:
+		ctors = []*ctor{{kind: ctorBool, b: true}, {kind: ctorBool, b: false}}
+	case bir.TyEnum:
+		enum := b.enumOf(ty)
+		if enum == nil {
+			return nil, false
+		}
+		for _, variant := range enum.Variants {
+			ctors = append(ctors, &ctor{kind: ctorVariant, enum: enum, variant: variant})
+		}
+	case bir.TyClass:
+		class := b.classOf(ty)
+		if class == nil {
+			return nil, false
+		}
+		return []*ctor{{kind: ctorClass, class: class}}, true
+	case bir.TyTuple:
+		return []*ctor{{kind: ctorTuple}}, true
+	case bir.TyInt:
+		ctors = splitCtor(&ctor{kind: ctorInt, lo: math.MinInt, hi: math.MaxInt}, rows)
+	default:
+		return nil, false
+	}
+
+	for _, c := range ctors {
+		if !isCovered(rows, c) {
+			return nil, false
+		}
+	}
+	return ctors, true
+}
+
+// missing returns a pattern for values of type ty that are not matched by
+// any of the constructors in the first column of rows.
+func (b *binder) missing(rows [][]*pat, ty *bir.Ty) *pat {
+	if len(heads(rows)) == 0 {
+		return &pat{}
+	}
+
+	var ctors []*ctor
+	switch ty.Kind {
+	case bir.TyBool:
+		ctors = []*ctor{{kind: ctorBool, b: true}, {kind: ctorBool, b: false}}
+	case bir.TyEnum:
+		if enum := b.enumOf(ty); enum != nil {
+			for _, variant := range enum.Variants {
+				ctors = append(ctors, &ctor{kind: ctorVariant, enum: enum, variant: variant})
+			}
+		}
+	}
+
+	for _, c := range ctors {
+		if !isCovered(rows, c) {
+			return &pat{ctor: c, fields: wilds(len(b.fieldTys(c, ty)))}
+		}
+	}
+	return &pat{}
+}
+
+// fieldTys returns the types of the fields of constructor c of type ty.
+func (b *binder) fieldTys(c *ctor, ty *bir.Ty) []*bir.Ty {
+	switch c.kind {
+	case ctorVariant:
+		return c.variant.Tys
+	case ctorClass:
+		subst := classSubst(c.class, ty)
+		tys := make([]*bir.Ty, len(c.class.Fields))
+		for i, field := range c.class.Fields {
+			tys[i] = field.Ty.Subst(subst)
+		}
+		return tys
+	case ctorTuple:
+		return ty.Elems
+	default:
+		return nil
+	}
+}
+
+// enumOf returns the enum of type ty. It is looked up among the items,
+// since a variable may shadow its name where ty is used.
+func (b *binder) enumOf(ty *bir.Ty) *bir.Enum {
+	def, _ := b.scope.Global().Get(ty.Enum.Name)
+	enum, _ := def.(*bir.Enum)
+	return enum
+}
+
+// classOf returns the class of type ty. It is looked up among the items,
+// since a variable may shadow its name where ty is used.
+func (b *binder) classOf(ty *bir.Ty) *bir.Class {
+	def, _ := b.scope.Global().Get(ty.Class.Name)
+	class, _ := def.(*bir.Class)
+	return class
+}
+
+// specialize returns the rows that match the values of constructor c,
+// with their first pattern replaced by the patterns of its n fields.
+func specialize(rows [][]*pat, c *ctor, n int) [][]*pat {
+	var spec [][]*pat
+	for _, row := range rows {
+		head := row[0]
+		if head.ctor == nil {
+			spec = append(spec, concatPats(wilds(n), row[1:]))
+		} else if covers(head.ctor, c) {
+			spec = append(spec, concatPats(head.fields, row[1:]))
+		}
+	}
+	return spec
+}
+
+// defaultRows returns the rows starting with a wildcard, without it.
+func defaultRows(rows [][]*pat) [][]*pat {
+	var def [][]*pat
+	for _, row := range rows {
+		if row[0].ctor == nil {
+			def = append(def, row[1:])
+		}
+	}
+	return def
+}
+
+// rebuild reverses the specialization of witness w by constructor c with n fields.
+func rebuild(c *ctor, n int, w []*pat) []*pat {
+	return concatPats([]*pat{{ctor: c, fields: w[:n]}}, w[n:])
+}
+
+// splitCtor splits integer range c into ranges that each are either
+// contained in or disjoint from every range in the first column of rows,
+// so that they can be specialized by. Other constructors are not split.
+func splitCtor(c *ctor, rows [][]*pat) []*ctor {
+	if c.kind != ctorInt {
+		return []*ctor{c}
+	}
+
+	starts := []int{c.lo}
+	for _, h := range heads(rows) {
+		if h.kind != ctorInt {
+			continue
+		}
+		if h.lo > c.lo && h.lo <= c.hi {
+			starts = append(starts, h.lo)
+		}
+		if h.hi >= c.lo && h.hi < c.hi {
+			starts = append(starts, h.hi+1)
+		}
+	}
+	sort.Ints(starts)
+
+	var ctors []*ctor
+	for i, lo := range starts {
+		if i > 0 && lo == starts[i-1] {
+			continue
+		}
+		hi := c.hi
+		for _, next := range starts[i+1:] {
+			if next != lo {
+				hi = next - 1
+				break
+			}
+		}
+		ctors = append(ctors, &ctor{kind: ctorInt, lo: lo, hi: hi})
+	}
+	return ctors
+}
+
+// covers reports whether every value constructed by c is also constructed by h.
+func covers(h, c *ctor) bool {
+	if h.kind != c.kind {
+		return false
+	}
+
+	switch h.kind {
+	case ctorBool:
+		return h.b == c.b
+	case ctorInt:
+		return h.lo <= c.lo && c.hi <= h.hi
+	case ctorString:
+		return h.s == c.s
+	case ctorVariant:
+		return h.variant == c.variant
+	default:
+		return true
+	}
+}
+
+// isCovered reports whether constructor c is covered by one in the first column of rows.
+func isCovered(rows [][]*pat, c *ctor) bool {
+	for _, h := range heads(rows) {
+		if covers(h, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// heads returns the constructors in the first column of rows.
+func heads(rows [][]*pat) []*ctor {
+	var ctors []*ctor
+	for _, row := range rows {
+		if row[0].ctor != nil {
+			ctors = append(ctors, row[0].ctor)
+		}
+	}
+	return ctors
+}
+
+func wilds(n int) []*pat {
+	pats := make([]*pat, n)
+	for i := range pats {
+		pats[i] = &pat{}
+	}
+	return pats
+}
+
+func concatPats(a, b []*pat) []*pat {
+	pats := make([]*pat, 0, len(a)+len(b))
+	pats = append(pats, a...)
+	return append(pats, b...)
+}
+
+func (p *pat) String() string {
+	if p.ctor == nil {
+		return "_"
+	}
+
+	c := p.ctor
+	switch c.kind {
+	case ctorBool:
+		return strconv.FormatBool(c.b)
+	case ctorInt:
+		if c.lo == c.hi {
+			return strconv.Itoa(c.lo)
+		}
+		return fmt.Sprintf("%d..=%d", c.lo, c.hi)
+	case ctorString:
+		return strconv.Quote(c.s)
+	case ctorVariant:
+		name := c.enum.Decl.Ident.Name + "." + c.variant.Ident.Name
+		if len(p.fields) == 0 {
+			return name
+		}
+		fields := make([]string, len(p.fields))
+		for i, f := range p.fields {
+			fields[i] = f.String()
+		}
+		return name + "(" + strings.Join(fields, ", ") + ")"
+	case ctorClass:
+		if len(p.fields) == 0 {
+			return c.class.Decl.Ident.Name + " {}"
+		}
+		fields := make([]string, len(p.fields))
+		for i, f := range p.fields {
+			fields[i] = c.class.Fields[i].Ident.Name + ": " + f.String()
+		}
+		return c.class.Decl.Ident.Name + " { " + strings.Join(fields, ", ") + " }"
+	case ctorTuple:
+		fields := make([]string, len(p.fields))
+		for i, f := range p.fields {
+			fields[i] = f.String()
+		}
+		if len(fields) == 1 {
+			return "(" + fields[0] + ",)"
+		}
+		return "(" + strings.Join(fields, ", ") + ")"
+	default:
+		panic("unreachable")
+	}
+}
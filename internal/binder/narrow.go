@@ -0,0 +1,202 @@
+package binder
+
+import (
+	"github.com/aadamandersson/lue/internal/ir/ast"
+	"github.com/aadamandersson/lue/internal/ir/bir"
+)
+
+// An optional variable that is known not to be `nil`.
+type narrowing struct {
+	name string
+	decl *bir.VarDecl
+}
+
+// narrowings returns the optional variables that are known not to be `nil`
+// when condition cond evaluates to truth, e.g., `x` in `x != nil` when it is true.
+func (b *binder) narrowings(cond ast.Expr, truth bool) []narrowing {
+	switch cond := cond.(type) {
+	case *ast.UnaryExpr:
+		if cond.Op.Kind == ast.Not {
+			return b.narrowings(cond.X, !truth)
+		}
+	case *ast.BinaryExpr:
+		switch cond.Op.Kind {
+		case ast.And:
+			if truth {
+				return append(b.narrowings(cond.X, true), b.narrowings(cond.Y, true)...)
+			}
+		case ast.Or:
+			if !truth {
+				return append(b.narrowings(cond.X, false), b.narrowings(cond.Y, false)...)
+			}
+		case ast.Eq, ast.Ne:
+			if (cond.Op.Kind == ast.Ne) == truth {
+				return b.nilCheck(cond)
+			}
+		}
+	}
+	return nil
+}
+
+// nilCheck returns the optional variable that is compared with `nil` in cond, if any.
+func (b *binder) nilCheck(cond *ast.BinaryExpr) []narrowing {
+	x, y := cond.X, cond.Y
+	if _, ok := x.(*ast.NilLiteral); ok {
+		x, y = y, x
+	}
+
+	ident, ok := x.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if _, ok := y.(*ast.NilLiteral); !ok {
+		return nil
+	}
+
+	// A variable captured by a closure may be set to `nil` by any call.
+	def, _ := b.scope.Get(ident.Name)
+	if decl, ok := def.(*bir.VarDecl); ok && decl.Ty.IsOptional() && !decl.Captured {
+		return []narrowing{{name: ident.Name, decl: decl}}
+	}
+	return nil
+}
+
+// narrow makes the variables in ns have the element type of
+// their optional type in the current scope.
+func (b *binder) narrow(ns []narrowing) {
+	for _, n := range ns {
+		b.scope.Insert(n.name, &bir.Narrowed{Decl: n.decl, Ty: n.decl.Ty.Elem})
+	}
+}
+
+// widenAssigned undoes the narrowing of the variables that exprs assign to.
+// It is used before the condition and body of a loop are bound, since an
+// assignment in one iteration may set a variable to `nil` before it is
+// used in the next one, even if the use comes first in the body.
+func (b *binder) widenAssigned(exprs ...ast.Expr) {
+	names := make(map[string]bool)
+	for _, expr := range exprs {
+		assigned(expr, names)
+	}
+	for name := range names {
+		def, _ := b.scope.Get(name)
+		if n, ok := def.(*bir.Narrowed); ok {
+			b.scope.Widen(n.Decl)
+		}
+	}
+}
+
+// assigned adds the names of the variables that are assigned to in expr,
+// including in the bodies of closures, to names.
+func assigned(expr ast.Expr, names map[string]bool) {
+	switch expr := expr.(type) {
+	case *ast.AssignExpr:
+		if ident, ok := expr.X.(*ast.Ident); ok {
+			names[ident.Name] = true
+		}
+		assigned(expr.X, names)
+		assigned(expr.Y, names)
+	case *ast.CompoundAssignExpr:
+		assigned(expr.X, names)
+		assigned(expr.Y, names)
+	case *ast.InterpExpr:
+		assignedAll(expr.Parts, names)
+	case *ast.UnaryExpr:
+		assigned(expr.X, names)
+	case *ast.BinaryExpr:
+		assigned(expr.X, names)
+		assigned(expr.Y, names)
+	case *ast.LetExpr:
+		assigned(expr.Init, names)
+	case *ast.FnExpr:
+		assigned(expr.Body, names)
+	case *ast.MatchExpr:
+		assigned(expr.X, names)
+		for _, arm := range expr.Arms {
+			assigned(arm.Guard, names)
+			assigned(arm.Body, names)
+		}
+	case *ast.IfExpr:
+		assigned(expr.Cond, names)
+		assigned(expr.Then, names)
+		assigned(expr.Else, names)
+	case *ast.BlockExpr:
+		assignedAll(expr.Exprs, names)
+	case *ast.CallExpr:
+		assigned(expr.Fn, names)
+		assignedAll(expr.Args, names)
+	case *ast.ClassExpr:
+		for _, f := range expr.Fields {
+			assigned(f.Expr, names)
+		}
+	case *ast.FieldExpr:
+		assigned(expr.Expr, names)
+	case *ast.ArrayExpr:
+		assignedAll(expr.Exprs, names)
+	case *ast.MapExpr:
+		for _, entry := range expr.Entries {
+			assigned(entry.Key, names)
+			assigned(entry.Val, names)
+		}
+	case *ast.TupleExpr:
+		assignedAll(expr.Exprs, names)
+	case *ast.IndexExpr:
+		assigned(expr.Arr, names)
+		assigned(expr.I, names)
+	case *ast.RangeExpr:
+		assigned(expr.Lo, names)
+		assigned(expr.Hi, names)
+	case *ast.ForExpr:
+		assigned(expr.Cond, names)
+		assigned(expr.Iter, names)
+		assigned(expr.Body, names)
+	case *ast.BreakExpr:
+		assigned(expr.X, names)
+	case *ast.ReturnExpr:
+		assigned(expr.X, names)
+	}
+}
+
+func assignedAll(exprs []ast.Expr, names map[string]bool) {
+	for _, expr := range exprs {
+		assigned(expr, names)
+	}
+}
+
+// diverges reports whether expr always returns, breaks or continues,
+// so that the code following it is not reached.
+func diverges(expr bir.Expr) bool {
+	switch expr := expr.(type) {
+	case *bir.ReturnExpr, *bir.BreakExpr, *bir.ContinueExpr:
+		return true
+	case *bir.BlockExpr:
+		for _, x := range expr.Exprs {
+			if diverges(x) {
+				return true
+			}
+		}
+		return false
+	case *bir.IfExpr:
+		return expr.Else != nil && diverges(expr.Then) && diverges(expr.Else)
+	default:
+		return false
+	}
+}
+
+// join returns the type of an expression that evaluates to a value of either
+// type x or y and a boolean true, if there is one. E.g., `int` and `nil` join to `int?`.
+// Otherwise, returns nil and a boolean false.
+func join(x, y *bir.Ty) (*bir.Ty, bool) {
+	switch {
+	case x.Accepts(y):
+		return x, true
+	case y.Accepts(x):
+		return y, true
+	case x.IsNil():
+		return bir.NewOptional(y), true
+	case y.IsNil():
+		return bir.NewOptional(x), true
+	default:
+		return nil, false
+	}
+}
@@ -5,8 +5,9 @@ import (
 )
 
 type Scope struct {
-	outer *Scope
-	defs  map[string]bir.Expr
+	outer   *Scope
+	defs    map[string]bir.Expr
+	closure *bir.FnExpr // Set if this is the outermost scope of a closure.
 }
 
 func NewScope() *Scope {
@@ -20,6 +21,14 @@ func WithOuter(outer *Scope) *Scope {
 	}
 }
 
+// WithClosure returns a new scope for the body of closure fn.
+// Variables that are found in scopes outside of it are recorded as captures of fn.
+func WithClosure(outer *Scope, fn *bir.FnExpr) *Scope {
+	s := WithOuter(outer)
+	s.closure = fn
+	return s
+}
+
 // Insert inserts the definition in to scope s and returns the shadowed definition and a boolean true, if any.
 // Otherwise, returns nil and a boolean false.
 func (s *Scope) Insert(name string, def bir.Expr) (bir.Expr, bool) {
@@ -35,14 +44,61 @@ func (s *Scope) Insert(name string, def bir.Expr) (bir.Expr, bool) {
 // If Get cannot find a definition associated with name in the current scope,
 // it will try to find it in the outer ones, if any.
 // Otherwise, returns nil and a boolean false.
+// A closure sees narrowed variables of enclosing functions with their declared type,
+// since it may be called after they have been set to `nil`. For the same reason,
+// the variables it captures are no longer narrowed outside of it either.
 func (s *Scope) Get(name string) (bir.Expr, bool) {
 	if d, ok := s.defs[name]; ok {
 		return d, true
 	}
-	if s.outer != nil {
-		return s.outer.Get(name)
+	if s.outer == nil {
+		return nil, false
 	}
-	return nil, false
+
+	d, ok := s.outer.Get(name)
+	if s.closure == nil {
+		return d, ok
+	}
+
+	if n, isNarrowed := d.(*bir.Narrowed); isNarrowed {
+		d = n.Decl
+		s.outer.Widen(n.Decl)
+	}
+	if decl, isVar := d.(*bir.VarDecl); isVar {
+		s.capture(decl)
+	}
+	return d, ok
+}
+
+// Global returns the outermost scope of s, which holds the items of the program.
+// Unlike the inner scopes, none of its definitions can be shadowed by variables.
+func (s *Scope) Global() *Scope {
+	for s.outer != nil {
+		s = s.outer
+	}
+	return s
+}
+
+// Widen undoes the narrowing of variable decl in scope s and its outer scopes,
+// after it has been assigned a value that may be `nil`.
+func (s *Scope) Widen(decl *bir.VarDecl) {
+	for sc := s; sc != nil; sc = sc.outer {
+		for name, d := range sc.defs {
+			if n, ok := d.(*bir.Narrowed); ok && n.Decl == decl {
+				sc.defs[name] = decl
+			}
+		}
+	}
+}
+
+func (s *Scope) capture(decl *bir.VarDecl) {
+	decl.Captured = true
+	for _, c := range s.closure.Captures {
+		if c == decl {
+			return
+		}
+	}
+	s.closure.Captures = append(s.closure.Captures, decl)
 }
 
 func (s *Scope) Functions() map[string]*bir.Fn {
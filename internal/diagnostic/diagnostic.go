@@ -13,9 +13,10 @@ type (
 	}
 
 	Diagnostic struct {
-		Msg    string
-		Span   span.Span
-		Labels []*Label
+		Msg      string
+		Span     span.Span
+		Labels   []*Label
+		Severity Severity
 	}
 
 	Label struct {
@@ -24,12 +25,27 @@ type (
 	}
 
 	Builder struct {
-		msg    string
-		span   span.Span
-		labels []*Label
+		msg      string
+		span     span.Span
+		labels   []*Label
+		severity Severity
 	}
 )
 
+type Severity int
+
+const (
+	Error   Severity = iota // Prevents the program from running.
+	Warning                 // Reported, but the program still runs.
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
 func NewBag() *Bag {
 	return &Bag{diags: make([]*Diagnostic, 0)}
 }
@@ -38,6 +54,16 @@ func (b *Bag) Empty() bool {
 	return len(b.diags) == 0
 }
 
+// HasErrors returns true if bag b contains any diagnostic with severity Error, otherwise false.
+func (b *Bag) HasErrors() bool {
+	for _, d := range b.diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *Bag) ForEach(f func(*Diagnostic) bool) {
 	for _, d := range b.diags {
 		if f(d) {
@@ -51,13 +77,13 @@ func (b *Bag) Dump(file *span.SourceFile) {
 	indent := func() { builder.WriteString(strings.Repeat(" ", 4)) }
 	for _, d := range b.diags {
 		builder.WriteByte('\n')
-		errStr := fmt.Sprintf("error: %s\n", d.Msg)
+		errStr := fmt.Sprintf("%s: %s\n", d.Severity, d.Msg)
 		builder.WriteString(errStr)
 
 		line := file.Line(d.Span.Start)
 		lineStart := file.LinePos(line)
 		lineEnd := file.LinePos(line + 1)
-		col := d.Span.Start - lineStart + 1
+		col := file.Column(d.Span.Start)
 		fLoc := fmt.Sprintf("[%s:%d:%d]\n", file.Name, col, line+1)
 		builder.WriteString(fLoc)
 
@@ -68,8 +94,7 @@ func (b *Bag) Dump(file *span.SourceFile) {
 			label := d.Labels[0] // FIXME: support multiple labels (secondary ones)
 
 			indent()
-			labelStart := label.Span.Start - lineStart
-			builder.WriteString(strings.Repeat(" ", labelStart))
+			builder.WriteString(file.Pad(label.Span.Start))
 			builder.WriteString("^ ")
 			builder.WriteString(label.Msg)
 			builder.WriteByte('\n')
@@ -100,11 +125,18 @@ func (b *Builder) WithLabel(msg string) *Builder {
 	return b
 }
 
+// AsWarning marks the diagnostic as a warning instead of an error.
+func (b *Builder) AsWarning() *Builder {
+	b.severity = Warning
+	return b
+}
+
 func (b *Builder) Build() *Diagnostic {
 	return &Diagnostic{
-		Msg:    b.msg,
-		Span:   b.span,
-		Labels: b.labels,
+		Msg:      b.msg,
+		Span:     b.span,
+		Labels:   b.labels,
+		Severity: b.severity,
 	}
 }
 
@@ -3,6 +3,7 @@ package ast
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/aadamandersson/lue/internal/ir"
 	"github.com/aadamandersson/lue/internal/span"
@@ -25,19 +26,30 @@ type (
 	// A function declaration.
 	// `fn ident([params]) [: ty] { exprs }`
 	FnDecl struct {
-		Ident *Ident
-		In    []*VarDecl
-		Out   *Ty
-		Body  Expr
-		Sp    span.Span
+		Ident    *Ident
+		TyParams []*Ident // Type parameters, e.g., `T` in `fn first<T>(xs: [T]): T`.
+		In       []*VarDecl
+		Out      *Ty
+		Body     Expr
+		Sp       span.Span
 	}
 
 	// A class declaration.
-	// `class ident { fields }`
+	// `class ident { fields methods }`
 	ClassDecl struct {
-		Ident  *Ident
-		Fields []*VarDecl
-		Sp     span.Span
+		Ident    *Ident
+		TyParams []*Ident // Type parameters, e.g., `T` in `class Box<T> { v: T }`.
+		Fields   []*VarDecl
+		Methods  []*FnDecl
+		Sp       span.Span
+	}
+
+	// An enum declaration.
+	// `enum ident { variants }`
+	EnumDecl struct {
+		Ident    *Ident
+		Variants []*Variant
+		Sp       span.Span
 	}
 
 	// Placeholder when we have some parse error.
@@ -55,13 +67,24 @@ const (
 	TyInfer TyKind = iota
 	TyArray
 	TyIdent
+	TySelf
+	TyFn
+	TyOptional
+	TyTuple
+	TyMap
 	TyUnit
 )
 
 type Ty struct {
-	Kind  TyKind
-	Ident *Ident // Nil if kind is `TyInfer` or `TyUnit`
-	Sp    span.Span
+	Kind   TyKind
+	Ident  *Ident // Nil unless kind is `TyIdent`.
+	Args   []*Ty  // Type arguments if kind is `TyIdent`, e.g., `int` in `Box<int>`.
+	Elem   *Ty    // Element type if kind is `TyArray` or `TyOptional`, value type if `TyMap`, otherwise nil.
+	Key    *Ty    // Key type if kind is `TyMap`, otherwise nil.
+	Params []*Ty  // Parameter types if kind is `TyFn`, otherwise nil.
+	Elems  []*Ty  // Element types if kind is `TyTuple`, otherwise nil.
+	Out    *Ty    // Return type if kind is `TyFn`, otherwise nil.
+	Sp     span.Span
 }
 
 func (t *Ty) String() string {
@@ -69,9 +92,45 @@ func (t *Ty) String() string {
 	case TyInfer:
 		return "?"
 	case TyArray:
-		return "[" + t.Ident.Name + "]"
+		return "[" + t.Elem.String() + "]"
+	case TyMap:
+		return "{" + t.Key.String() + ": " + t.Elem.String() + "}"
 	case TyIdent:
-		return t.Ident.Name
+		if len(t.Args) == 0 {
+			return t.Ident.Name
+		}
+		args := make([]string, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = arg.String()
+		}
+		return t.Ident.Name + "<" + strings.Join(args, ", ") + ">"
+	case TySelf:
+		return "self"
+	case TyFn:
+		var builder strings.Builder
+		builder.WriteString("fn(")
+		for i, param := range t.Params {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteString(param.String())
+		}
+		builder.WriteByte(')')
+		if t.Out.Kind != TyUnit {
+			builder.WriteString(": " + t.Out.String())
+		}
+		return builder.String()
+	case TyOptional:
+		return t.Elem.String() + "?"
+	case TyTuple:
+		elems := make([]string, len(t.Elems))
+		for i, elem := range t.Elems {
+			elems[i] = elem.String()
+		}
+		if len(elems) == 1 {
+			return "(" + elems[0] + ",)"
+		}
+		return "(" + strings.Join(elems, ", ") + ")"
 	case TyUnit:
 		return "()"
 	default:
@@ -82,8 +141,17 @@ func (t *Ty) String() string {
 // Ensure that we can only assign item nodes to an Item.
 func (*FnDecl) isItem()    {}
 func (*ClassDecl) isItem() {}
+func (*EnumDecl) isItem()  {}
 func (*ErrItem) isItem()   {}
 
+// An enum variant with an optional payload.
+// `ident [(ty, ...)]`
+type Variant struct {
+	Ident *Ident
+	Tys   []*Ty
+	Sp    span.Span
+}
+
 // `ident: expr`
 type ExprField struct {
 	Ident *Ident
@@ -91,6 +159,104 @@ type ExprField struct {
 	Sp    span.Span
 }
 
+// `key: val`
+type MapEntry struct {
+	Key Expr
+	Val Expr
+}
+
+// An arm of a match expression.
+// `pat [if guard] => body`
+type MatchArm struct {
+	Pat   Pat
+	Guard Expr // Optional, may be nil.
+	Body  Expr
+	Sp    span.Span
+}
+
+type Pat interface {
+	Span() span.Span
+	isPat()
+}
+
+// Patterns
+type (
+	// A wildcard pattern, which matches anything.
+	// `_`
+	WildPat struct {
+		Sp span.Span
+	}
+
+	// A binding pattern, which matches anything and binds it to ident.
+	// E.g., `n`
+	BindPat struct {
+		Ident *Ident
+	}
+
+	// A literal pattern.
+	// E.g., `1`, `-1`, `"foo"` or `true`
+	LitPat struct {
+		X  Expr
+		Sp span.Span
+	}
+
+	// An integer range pattern.
+	// `lo..hi` or `lo..=hi`
+	RangePat struct {
+		Lo        Expr
+		Hi        Expr
+		Inclusive bool
+		Sp        span.Span
+	}
+
+	// A class pattern, fields that are left out match anything.
+	// `ident { ident [: pat], ... }`
+	ClassPat struct {
+		Ident  *Ident
+		Fields []*PatField
+		Sp     span.Span
+	}
+
+	// A tuple pattern.
+	// `(pats)`
+	TuplePat struct {
+		Pats []Pat
+		Sp   span.Span
+	}
+
+	// An enum variant pattern.
+	// `enum.variant[(pats)]`
+	VariantPat struct {
+		Enum    *Ident
+		Variant *Ident
+		Pats    []Pat
+		Sp      span.Span
+	}
+)
+
+// `ident [: pat]`
+type PatField struct {
+	Ident *Ident
+	Pat   Pat // A binding pattern named ident if left out.
+}
+
+// Ensure that we can only assign pattern nodes to a Pat.
+func (*WildPat) isPat()    {}
+func (*BindPat) isPat()    {}
+func (*LitPat) isPat()     {}
+func (*RangePat) isPat()   {}
+func (*ClassPat) isPat()   {}
+func (*TuplePat) isPat()   {}
+func (*VariantPat) isPat() {}
+
+func (p *WildPat) Span() span.Span    { return p.Sp }
+func (p *BindPat) Span() span.Span    { return p.Ident.Sp }
+func (p *LitPat) Span() span.Span     { return p.Sp }
+func (p *RangePat) Span() span.Span   { return p.Sp }
+func (p *ClassPat) Span() span.Span   { return p.Sp }
+func (p *TuplePat) Span() span.Span   { return p.Sp }
+func (p *VariantPat) Span() span.Span { return p.Sp }
+
 // Expressions
 type (
 	// An identifier.
@@ -104,6 +270,13 @@ type (
 		Sp span.Span
 	}
 
+	// A float literal.
+	// E.g., `1.5`
+	FloatLiteral struct {
+		V  string
+		Sp span.Span
+	}
+
 	// A boolean literal.
 	// `true` or `false`
 	BooleanLiteral struct {
@@ -118,6 +291,28 @@ type (
 		Sp span.Span
 	}
 
+	// An interpolated string, whose parts are string literals and
+	// the expressions between them.
+	// E.g., `"x = {x}"`
+	InterpExpr struct {
+		Parts []Expr
+		Sp    span.Span
+	}
+
+	// The absence of a value of an optional type.
+	// `nil`
+	NilLiteral struct {
+		Sp span.Span
+	}
+
+	// A unary expression.
+	// E.g., `-x`
+	UnaryExpr struct {
+		Op UnOp
+		X  Expr
+		Sp span.Span
+	}
+
 	// A binary expression.
 	// E.g., `x + y`
 	BinaryExpr struct {
@@ -128,9 +323,10 @@ type (
 	}
 
 	// A let binding.
-	// `let ident [: ty] = init`
+	// `let ident [: ty] = init` or `let (pats) [: ty] = init`
 	LetExpr struct {
 		Decl *VarDecl
+		Pat  Pat // The pattern of a destructuring let, in which case Decl.Ident is nil.
 		Init Expr
 		Sp   span.Span
 	}
@@ -143,6 +339,32 @@ type (
 		Sp span.Span
 	}
 
+	// An anonymous function literal.
+	// `fn(params) [: ty] { exprs }`
+	FnExpr struct {
+		In   []*VarDecl
+		Out  *Ty
+		Body Expr
+		Sp   span.Span
+	}
+
+	// A compound assignment expression.
+	// `x op= y`
+	CompoundAssignExpr struct {
+		X  Expr
+		Op BinOp // The arithmetic operator, e.g., `+` for `+=`.
+		Y  Expr
+		Sp span.Span
+	}
+
+	// A match expression.
+	// `match x { arms }`
+	MatchExpr struct {
+		X    Expr
+		Arms []*MatchArm
+		Sp   span.Span
+	}
+
 	// An if expression.
 	// `if cond { exprs } [else [if cond] { exprs }]`
 	IfExpr struct {
@@ -175,7 +397,7 @@ type (
 		Sp     span.Span
 	}
 
-	// A field expression.
+	// A field expression, or a tuple index if ident is a number.
 	// `expr.ident`
 	FieldExpr struct {
 		Expr  Expr
@@ -190,6 +412,20 @@ type (
 		Sp    span.Span
 	}
 
+	// A map expression.
+	// `{"foo": 1, "bar": 2}`
+	MapExpr struct {
+		Entries []*MapEntry
+		Sp      span.Span
+	}
+
+	// A tuple expression.
+	// `(1, "foo")`
+	TupleExpr struct {
+		Exprs []Expr
+		Sp    span.Span
+	}
+
 	// An array indexing expression.
 	// `arr[i]`
 	IndexExpr struct {
@@ -198,18 +434,40 @@ type (
 		Sp  span.Span
 	}
 
+	// A range expression.
+	// `lo..hi` or `lo..=hi`
+	RangeExpr struct {
+		Lo        Expr
+		Hi        Expr
+		Inclusive bool
+		Sp        span.Span
+	}
+
 	// A for loop.
-	// `for { exprs }`
+	// `['label:] for [cond | ident in iter] { exprs }`
 	ForExpr struct {
-		Body Expr
-		Sp   span.Span
+		Label *Ident // Optional, may be nil.
+		Cond  Expr   // Optional, may be nil.
+		Ident *Ident // Loop variable, nil unless this is a `for ident in iter` loop.
+		Val   *Ident // Second loop variable in `for key, val in map`, may be nil.
+		Iter  Expr   // Optional, may be nil.
+		Body  Expr
+		Sp    span.Span
 	}
 
 	// A break expression.
-	// `break [expr]`
+	// `break ['label] [expr]`
 	BreakExpr struct {
-		X  Expr // Optional, may be nil.
-		Sp span.Span
+		Label *Ident // Optional, may be nil.
+		X     Expr   // Optional, may be nil.
+		Sp    span.Span
+	}
+
+	// A continue expression.
+	// `continue ['label]`
+	ContinueExpr struct {
+		Label *Ident // Optional, may be nil.
+		Sp    span.Span
 	}
 
 	// A return expression.
@@ -226,43 +484,106 @@ type (
 )
 
 // Ensure that we can only assign expression nodes to an Expr.
-func (*Ident) isExpr()          {}
-func (*IntegerLiteral) isExpr() {}
-func (*BooleanLiteral) isExpr() {}
-func (*StringLiteral) isExpr()  {}
-func (*BinaryExpr) isExpr()     {}
-func (*LetExpr) isExpr()        {}
-func (*AssignExpr) isExpr()     {}
-func (*IfExpr) isExpr()         {}
-func (*BlockExpr) isExpr()      {}
-func (*CallExpr) isExpr()       {}
-func (*ClassExpr) isExpr()      {}
-func (*FieldExpr) isExpr()      {}
-func (*ArrayExpr) isExpr()      {}
-func (*IndexExpr) isExpr()      {}
-func (*ForExpr) isExpr()        {}
-func (*BreakExpr) isExpr()      {}
-func (*ReturnExpr) isExpr()     {}
-func (*ErrExpr) isExpr()        {}
-
-func (e *Ident) Span() span.Span          { return e.Sp }
-func (e *IntegerLiteral) Span() span.Span { return e.Sp }
-func (e *BooleanLiteral) Span() span.Span { return e.Sp }
-func (e *StringLiteral) Span() span.Span  { return e.Sp }
-func (e *BinaryExpr) Span() span.Span     { return e.Sp }
-func (e *LetExpr) Span() span.Span        { return e.Sp }
-func (e *AssignExpr) Span() span.Span     { return e.Sp }
-func (e *IfExpr) Span() span.Span         { return e.Sp }
-func (e *BlockExpr) Span() span.Span      { return e.Sp }
-func (e *CallExpr) Span() span.Span       { return e.Sp }
-func (e *ClassExpr) Span() span.Span      { return e.Sp }
-func (e *FieldExpr) Span() span.Span      { return e.Sp }
-func (e *ArrayExpr) Span() span.Span      { return e.Sp }
-func (e *IndexExpr) Span() span.Span      { return e.Sp }
-func (e *ForExpr) Span() span.Span        { return e.Sp }
-func (e *BreakExpr) Span() span.Span      { return e.Sp }
-func (e *ReturnExpr) Span() span.Span     { return e.Sp }
-func (e *ErrExpr) Span() span.Span        { return e.Sp }
+func (*Ident) isExpr()              {}
+func (*IntegerLiteral) isExpr()     {}
+func (*FloatLiteral) isExpr()       {}
+func (*BooleanLiteral) isExpr()     {}
+func (*StringLiteral) isExpr()      {}
+func (*InterpExpr) isExpr()         {}
+func (*NilLiteral) isExpr()         {}
+func (*UnaryExpr) isExpr()          {}
+func (*BinaryExpr) isExpr()         {}
+func (*LetExpr) isExpr()            {}
+func (*AssignExpr) isExpr()         {}
+func (*CompoundAssignExpr) isExpr() {}
+func (*FnExpr) isExpr()             {}
+func (*MatchExpr) isExpr()          {}
+func (*IfExpr) isExpr()             {}
+func (*BlockExpr) isExpr()          {}
+func (*CallExpr) isExpr()           {}
+func (*ClassExpr) isExpr()          {}
+func (*FieldExpr) isExpr()          {}
+func (*ArrayExpr) isExpr()          {}
+func (*MapExpr) isExpr()            {}
+func (*TupleExpr) isExpr()          {}
+func (*IndexExpr) isExpr()          {}
+func (*RangeExpr) isExpr()          {}
+func (*ForExpr) isExpr()            {}
+func (*BreakExpr) isExpr()          {}
+func (*ContinueExpr) isExpr()       {}
+func (*ReturnExpr) isExpr()         {}
+func (*ErrExpr) isExpr()            {}
+
+func (e *Ident) Span() span.Span              { return e.Sp }
+func (e *IntegerLiteral) Span() span.Span     { return e.Sp }
+func (e *FloatLiteral) Span() span.Span       { return e.Sp }
+func (e *BooleanLiteral) Span() span.Span     { return e.Sp }
+func (e *StringLiteral) Span() span.Span      { return e.Sp }
+func (e *InterpExpr) Span() span.Span         { return e.Sp }
+func (e *NilLiteral) Span() span.Span         { return e.Sp }
+func (e *UnaryExpr) Span() span.Span          { return e.Sp }
+func (e *BinaryExpr) Span() span.Span         { return e.Sp }
+func (e *LetExpr) Span() span.Span            { return e.Sp }
+func (e *AssignExpr) Span() span.Span         { return e.Sp }
+func (e *CompoundAssignExpr) Span() span.Span { return e.Sp }
+func (e *FnExpr) Span() span.Span             { return e.Sp }
+func (e *MatchExpr) Span() span.Span          { return e.Sp }
+func (e *IfExpr) Span() span.Span             { return e.Sp }
+func (e *BlockExpr) Span() span.Span          { return e.Sp }
+func (e *CallExpr) Span() span.Span           { return e.Sp }
+func (e *ClassExpr) Span() span.Span          { return e.Sp }
+func (e *FieldExpr) Span() span.Span          { return e.Sp }
+func (e *ArrayExpr) Span() span.Span          { return e.Sp }
+func (e *MapExpr) Span() span.Span            { return e.Sp }
+func (e *TupleExpr) Span() span.Span          { return e.Sp }
+func (e *IndexExpr) Span() span.Span          { return e.Sp }
+func (e *RangeExpr) Span() span.Span          { return e.Sp }
+func (e *ForExpr) Span() span.Span            { return e.Sp }
+func (e *BreakExpr) Span() span.Span          { return e.Sp }
+func (e *ContinueExpr) Span() span.Span       { return e.Sp }
+func (e *ReturnExpr) Span() span.Span         { return e.Sp }
+func (e *ErrExpr) Span() span.Span            { return e.Sp }
+
+type UnOp struct {
+	Kind UnOpKind
+	Sp   span.Span
+}
+
+// UnOpFromToken returns the unOp for token t and a boolean true, if its a valid unary operator.
+// Otherwise, returns the zero value of UnOp and a boolean false.
+func UnOpFromToken(t token.Token) (UnOp, bool) {
+	var kind UnOpKind
+	isUnOp := true
+
+	switch t.Kind {
+	case token.Minus:
+		kind = Neg
+	case token.Bang:
+		kind = Not
+	default:
+		isUnOp = false
+	}
+	return UnOp{Kind: kind, Sp: t.Sp}, isUnOp
+}
+
+type UnOpKind int
+
+const (
+	Neg UnOpKind = iota // `-` (negation)
+	Not                 // `!` (logical not)
+)
+
+var unOps = [...]string{
+	Neg: "-",
+	Not: "!",
+}
+
+func (op UnOpKind) String() string {
+	if op < 0 || op >= UnOpKind(len(unOps)) {
+		return "UnOpKind(" + strconv.FormatInt(int64(op), 10) + ")"
+	}
+	return unOps[op]
+}
 
 type BinOp struct {
 	Kind BinOpKind
@@ -284,6 +605,18 @@ func BinOpFromToken(t token.Token) (BinOp, bool) {
 		kind = Mul
 	case token.Slash:
 		kind = Div
+	case token.Percent:
+		kind = Rem
+	case token.Amp:
+		kind = BitAnd
+	case token.Pipe:
+		kind = BitOr
+	case token.Caret:
+		kind = BitXor
+	case token.Shl:
+		kind = Shl
+	case token.Shr:
+		kind = Shr
 	case token.Gt:
 		kind = Gt
 	case token.Lt:
@@ -296,8 +629,26 @@ func BinOpFromToken(t token.Token) (BinOp, bool) {
 		kind = Eq
 	case token.Ne:
 		kind = Ne
+	case token.AmpAmp:
+		kind = And
+	case token.PipePipe:
+		kind = Or
 	case token.Eq:
 		kind = Assign
+	case token.PlusEq:
+		kind = AddAssign
+	case token.MinusEq:
+		kind = SubAssign
+	case token.StarEq:
+		kind = MulAssign
+	case token.SlashEq:
+		kind = DivAssign
+	case token.DotDot:
+		kind = Range
+	case token.DotDotEq:
+		kind = RangeInclusive
+	case token.In:
+		kind = In
 	default:
 		isBinOp = false
 	}
@@ -307,19 +658,51 @@ func BinOpFromToken(t token.Token) (BinOp, bool) {
 // Prec returns the operator precedence for binary operator op.
 func (op BinOp) Prec() int {
 	switch op.Kind {
-	case Mul, Div:
-		return 4
+	case Mul, Div, Rem:
+		return 11
 	case Add, Sub:
+		return 10
+	case Shl, Shr:
+		return 9
+	case BitAnd:
+		return 8
+	case BitXor:
+		return 7
+	case BitOr:
+		return 6
+	case Gt, Lt, Ge, Le, Eq, Ne, In:
+		return 5
+	case And:
+		return 4
+	case Or:
 		return 3
-	case Gt, Lt, Ge, Le, Eq, Ne:
+	case Range, RangeInclusive:
 		return 2
-	case Assign:
+	case Assign, AddAssign, SubAssign, MulAssign, DivAssign:
 		return 1
 	default:
 		return 0
 	}
 }
 
+// CompoundOp returns the arithmetic operator applied by compound assignment
+// operator op, e.g., `+` for `+=`, and a boolean true.
+// Otherwise, returns op and a boolean false.
+func (op BinOp) CompoundOp() (BinOp, bool) {
+	switch op.Kind {
+	case AddAssign:
+		return BinOp{Kind: Add, Sp: op.Sp}, true
+	case SubAssign:
+		return BinOp{Kind: Sub, Sp: op.Sp}, true
+	case MulAssign:
+		return BinOp{Kind: Mul, Sp: op.Sp}, true
+	case DivAssign:
+		return BinOp{Kind: Div, Sp: op.Sp}, true
+	default:
+		return op, false
+	}
+}
+
 type Assoc int
 
 const (
@@ -330,9 +713,10 @@ const (
 // Assoc returns the associativity of the binary operator op.
 func (op BinOp) Assoc() Assoc {
 	switch op.Kind {
-	case Assign:
+	case Assign, AddAssign, SubAssign, MulAssign, DivAssign:
 		return AssocRight
-	case Add, Sub, Mul, Div, Gt, Lt, Ge, Le, Eq, Ne:
+	case Add, Sub, Mul, Div, Rem, BitAnd, BitOr, BitXor, Shl, Shr,
+		Gt, Lt, Ge, Le, Eq, Ne, In, And, Or, Range, RangeInclusive:
 		return AssocLeft
 	}
 	panic(fmt.Sprintf("`%s` is not a valid binary operator\n", op.Kind.String()))
@@ -341,31 +725,61 @@ func (op BinOp) Assoc() Assoc {
 type BinOpKind int
 
 const (
-	Add    BinOpKind = iota // `+` (addition)
-	Sub                     // `-` (subtraction)
-	Mul                     // `*` (multiplication)
-	Div                     // `/` (division)
-	Gt                      // `>` (greater than)
-	Lt                      // `<` (less than)
-	Ge                      // `>=` (greater than or equal)
-	Le                      // `<=` (less than or equal)
-	Eq                      // `==` (equality)
-	Ne                      // `!=` (not equal)
-	Assign                  // `=` (assignment)
+	Add            BinOpKind = iota // `+` (addition)
+	Sub                             // `-` (subtraction)
+	Mul                             // `*` (multiplication)
+	Div                             // `/` (division)
+	Rem                             // `%` (remainder)
+	BitAnd                          // `&` (bitwise and)
+	BitOr                           // `|` (bitwise or)
+	BitXor                          // `^` (bitwise xor)
+	Shl                             // `<<` (shift left)
+	Shr                             // `>>` (shift right)
+	Gt                              // `>` (greater than)
+	Lt                              // `<` (less than)
+	Ge                              // `>=` (greater than or equal)
+	Le                              // `<=` (less than or equal)
+	Eq                              // `==` (equality)
+	Ne                              // `!=` (not equal)
+	And                             // `&&` (logical and)
+	Or                              // `||` (logical or)
+	Assign                          // `=` (assignment)
+	AddAssign                       // `+=` (addition assignment)
+	SubAssign                       // `-=` (subtraction assignment)
+	MulAssign                       // `*=` (multiplication assignment)
+	DivAssign                       // `/=` (division assignment)
+	Range                           // `..` (exclusive range)
+	RangeInclusive                  // `..=` (inclusive range)
+	In                              // `in` (map key presence)
 )
 
 var binOps = [...]string{
-	Add:    "+",
-	Sub:    "-",
-	Mul:    "*",
-	Div:    "/",
-	Gt:     ">",
-	Lt:     "<",
-	Ge:     ">=",
-	Le:     "<=",
-	Eq:     "==",
-	Ne:     "!=",
-	Assign: "=",
+	Add:            "+",
+	Sub:            "-",
+	Mul:            "*",
+	Div:            "/",
+	Rem:            "%",
+	BitAnd:         "&",
+	BitOr:          "|",
+	BitXor:         "^",
+	Shl:            "<<",
+	Shr:            ">>",
+	Gt:             ">",
+	Lt:             "<",
+	Ge:             ">=",
+	Le:             "<=",
+	Eq:             "==",
+	Ne:             "!=",
+	And:            "&&",
+	Or:             "||",
+	Assign:         "=",
+	AddAssign:      "+=",
+	SubAssign:      "-=",
+	MulAssign:      "*=",
+	DivAssign:      "/=",
+	Range:          "..",
+	RangeInclusive: "..=",
+	In:             "in",
 }
 
 func (op BinOpKind) String() string {
@@ -1,8 +1,11 @@
 package bir
 
 import (
+	"strings"
+
 	"github.com/aadamandersson/lue/internal/ir"
 	"github.com/aadamandersson/lue/internal/ir/ast"
+	"github.com/aadamandersson/lue/internal/span"
 )
 
 type (
@@ -19,26 +22,57 @@ type ExprField struct {
 	Expr  Expr
 }
 
+// `key: val`
+type MapEntry struct {
+	Key Expr
+	Val Expr
+}
+
 type (
 	// A reference to a function.
 	Fn struct {
-		Decl *ast.FnDecl
-		In   []*VarDecl
-		Out  *Ty
-		Body Expr
+		Decl     *ast.FnDecl
+		TyParams []*Ty // Inferred anew at each call.
+		In       []*VarDecl
+		Out      *Ty
+		Body     Expr
 	}
 
 	// A reference to a class.
 	Class struct {
-		Decl   *ast.ClassDecl
-		Fields []*VarDecl
+		Decl     *ast.ClassDecl
+		TyParams []*Ty
+		Fields   []*VarDecl
+		Methods  []*Fn
+	}
+
+	// A reference to an enum.
+	Enum struct {
+		Decl     *ast.EnumDecl
+		Variants []*Variant
+	}
+
+	// A reference to a method bound to a receiver.
+	// `expr.ident`
+	MethodExpr struct {
+		Recv Expr
+		Fn   *Fn
+		Ty   *Ty // The type of the method without `self`, instantiated for the receiver.
 	}
 
 	// A variable declaration.
 	// `ident: ty`
 	VarDecl struct {
-		Ident *ir.Ident
-		Ty    *Ty
+		Ident    *ir.Ident
+		Ty       *Ty
+		Captured bool // Set once a closure captures the variable.
+	}
+
+	// A reference to an optional variable that is known not to be `nil`,
+	// e.g., `x` in the body of `if x != nil { x }`.
+	Narrowed struct {
+		Decl *VarDecl
+		Ty   *Ty // The element type of the type of Decl.
 	}
 
 	// An integer literal.
@@ -47,6 +81,12 @@ type (
 		V int
 	}
 
+	// A float literal.
+	// E.g., `1.5`
+	FloatLiteral struct {
+		V float64
+	}
+
 	// A boolean literal.
 	// `true` or `false`
 	BooleanLiteral struct {
@@ -59,18 +99,38 @@ type (
 		V string
 	}
 
+	// An interpolated string, whose parts are string literals and
+	// the expressions between them.
+	// E.g., `"x = {x}"`
+	InterpExpr struct {
+		Parts []Expr
+	}
+
+	// The absence of a value of an optional type.
+	// `nil`
+	NilLiteral struct{}
+
+	// A unary expression.
+	// E.g., `-x`
+	UnaryExpr struct {
+		Op UnOp
+		X  Expr
+	}
+
 	// A binary expression.
 	// E.g., `x + y`
 	BinaryExpr struct {
 		X  Expr
 		Op BinOp
 		Y  Expr
+		Sp span.Span
 	}
 
 	// A let binding.
-	// `let ident [: ty] = init`
+	// `let ident [: ty] = init` or `let (pats) [: ty] = init`
 	LetExpr struct {
-		Decl *VarDecl
+		Decl *VarDecl // Nil if this is a destructuring let.
+		Pat  Pat      // The pattern of a destructuring let, otherwise nil.
 		Init Expr
 	}
 
@@ -81,12 +141,47 @@ type (
 		Y Expr
 	}
 
+	// An anonymous function literal.
+	// `fn(params) [: ty] { exprs }`
+	FnExpr struct {
+		In       []*VarDecl
+		Out      *Ty
+		Body     Expr
+		Captures []*VarDecl // Variables of enclosing functions used in the body.
+	}
+
+	// An enum variant constructed from its payload, if any.
+	// `enum.variant[(args)]`
+	VariantExpr struct {
+		Enum    *Enum
+		Variant *Variant
+		Args    []Expr
+	}
+
+	// A compound assignment expression.
+	// `x op= y`
+	CompoundAssignExpr struct {
+		X  Expr
+		Op BinOp
+		Y  Expr
+		Sp span.Span
+	}
+
+	// A match expression.
+	// `match x { arms }`
+	MatchExpr struct {
+		X    Expr
+		Arms []*MatchArm
+		Ty   *Ty
+	}
+
 	// An if expression.
 	// `if cond { exprs } [else [if cond] { exprs }]`
 	IfExpr struct {
 		Cond Expr
 		Then Expr
 		Else Expr // Optional, may be nil.
+		Ty   *Ty
 	}
 
 	// A block expression.
@@ -100,12 +195,14 @@ type (
 	CallExpr struct {
 		Fn   Expr
 		Args []Expr
+		Ty   *Ty
 	}
 
 	// A class literal expression.
 	// `class {a: 1, b: 2}`
 	ClassExpr struct {
 		Ident  *ir.Ident
+		Args   []*Ty // Inferred type arguments of a generic class.
 		Fields []*ExprField
 	}
 
@@ -123,23 +220,71 @@ type (
 		Exprs []Expr
 	}
 
+	// A map expression.
+	// `{"foo": 1, "bar": 2}`
+	MapExpr struct {
+		Entries []*MapEntry
+	}
+
+	// A tuple expression.
+	// `(1, "foo")`
+	TupleExpr struct {
+		Exprs []Expr
+	}
+
+	// A tuple indexing expression.
+	// E.g., `t.0`
+	TupleIndexExpr struct {
+		X Expr
+		I int
+	}
+
 	// An array indexing expression.
 	// `arr[i]`
 	IndexExpr struct {
 		Arr Expr
 		I   Expr
+		Sp  span.Span
+	}
+
+	// A map indexing expression.
+	// `m[key]`
+	MapIndexExpr struct {
+		Map Expr
+		Key Expr
+		Sp  span.Span
+	}
+
+	// A range expression.
+	// `lo..hi` or `lo..=hi`
+	RangeExpr struct {
+		Lo        Expr
+		Hi        Expr
+		Inclusive bool
 	}
 
 	// A for loop.
-	// `for { exprs }`
+	// `['label:] for [cond | ident in iter] { exprs }`
 	ForExpr struct {
-		Body Expr
+		Label *ir.Ident // Optional, may be nil.
+		Cond  Expr      // Optional, may be nil.
+		Var   *VarDecl  // Loop variable, nil unless this is a `for ident in iter` loop.
+		Val   *VarDecl  // Second loop variable in `for key, val in map`, may be nil.
+		Iter  Expr      // Optional, may be nil.
+		Body  Expr
 	}
 
 	// A break expression.
-	// `break [expr]`
+	// `break ['label] [expr]`
 	BreakExpr struct {
-		X Expr // Optional, may be nil.
+		X    Expr     // Optional, may be nil.
+		Loop *ForExpr // The loop to break out of.
+	}
+
+	// A continue expression.
+	// `continue ['label]`
+	ContinueExpr struct {
+		Loop *ForExpr // The loop to continue.
 	}
 
 	// A return expression.
@@ -157,46 +302,86 @@ type (
 )
 
 // Ensure that we can only assign expression nodes to an Expr.
-func (*Fn) isExpr()             {}
-func (*Class) isExpr()          {}
-func (*VarDecl) isExpr()        {}
-func (*IntegerLiteral) isExpr() {}
-func (*BooleanLiteral) isExpr() {}
-func (*StringLiteral) isExpr()  {}
-func (*BinaryExpr) isExpr()     {}
-func (*LetExpr) isExpr()        {}
-func (*AssignExpr) isExpr()     {}
-func (*IfExpr) isExpr()         {}
-func (*BlockExpr) isExpr()      {}
-func (*CallExpr) isExpr()       {}
-func (*ClassExpr) isExpr()      {}
-func (*FieldExpr) isExpr()      {}
-func (*ArrayExpr) isExpr()      {}
-func (*IndexExpr) isExpr()      {}
-func (*ForExpr) isExpr()        {}
-func (*BreakExpr) isExpr()      {}
-func (*ReturnExpr) isExpr()     {}
-func (Intrinsic) isExpr()       {}
-func (*ErrExpr) isExpr()        {}
-
-func (e *Fn) Type() *Ty             { return e.Out }
-func (e *Class) Type() *Ty          { return NewClass((*ir.Ident)(e.Decl.Ident)) }
-func (e *VarDecl) Type() *Ty        { return e.Ty }
-func (e *IntegerLiteral) Type() *Ty { return BasicTys[TyInt] }
-func (e *BooleanLiteral) Type() *Ty { return BasicTys[TyBool] }
-func (e *StringLiteral) Type() *Ty  { return BasicTys[TyString] }
-func (e *BinaryExpr) Type() *Ty     { return e.Op.Ty }
-func (e *LetExpr) Type() *Ty        { return BasicTys[TyUnit] }
-func (e *AssignExpr) Type() *Ty     { return BasicTys[TyUnit] }
-func (e *IfExpr) Type() *Ty         { return e.Then.Type() }
+func (*Fn) isExpr()                 {}
+func (*Class) isExpr()              {}
+func (*Enum) isExpr()               {}
+func (*VariantExpr) isExpr()        {}
+func (*MethodExpr) isExpr()         {}
+func (*VarDecl) isExpr()            {}
+func (*Narrowed) isExpr()           {}
+func (*IntegerLiteral) isExpr()     {}
+func (*FloatLiteral) isExpr()       {}
+func (*BooleanLiteral) isExpr()     {}
+func (*StringLiteral) isExpr()      {}
+func (*InterpExpr) isExpr()         {}
+func (*NilLiteral) isExpr()         {}
+func (*UnaryExpr) isExpr()          {}
+func (*BinaryExpr) isExpr()         {}
+func (*LetExpr) isExpr()            {}
+func (*AssignExpr) isExpr()         {}
+func (*CompoundAssignExpr) isExpr() {}
+func (*FnExpr) isExpr()             {}
+func (*MatchExpr) isExpr()          {}
+func (*IfExpr) isExpr()             {}
+func (*BlockExpr) isExpr()          {}
+func (*CallExpr) isExpr()           {}
+func (*ClassExpr) isExpr()          {}
+func (*FieldExpr) isExpr()          {}
+func (*ArrayExpr) isExpr()          {}
+func (*MapExpr) isExpr()            {}
+func (*MapIndexExpr) isExpr()       {}
+func (*TupleExpr) isExpr()          {}
+func (*TupleIndexExpr) isExpr()     {}
+func (*IndexExpr) isExpr()          {}
+func (*RangeExpr) isExpr()          {}
+func (*ForExpr) isExpr()            {}
+func (*BreakExpr) isExpr()          {}
+func (*ContinueExpr) isExpr()       {}
+func (*ReturnExpr) isExpr()         {}
+func (Intrinsic) isExpr()           {}
+func (*ErrExpr) isExpr()            {}
+
+func (e *Fn) Type() *Ty {
+	params := make([]*Ty, len(e.In))
+	for i, param := range e.In {
+		params[i] = param.Ty
+	}
+	return NewFn(params, e.Out)
+}
+func (e *Class) Type() *Ty              { return NewClass((*ir.Ident)(e.Decl.Ident), e.TyParams) }
+func (e *Enum) Type() *Ty               { return NewEnum((*ir.Ident)(e.Decl.Ident)) }
+func (e *VariantExpr) Type() *Ty        { return e.Enum.Type() }
+func (e *MethodExpr) Type() *Ty         { return e.Ty }
+func (e *VarDecl) Type() *Ty            { return e.Ty }
+func (e *Narrowed) Type() *Ty           { return e.Ty }
+func (e *IntegerLiteral) Type() *Ty     { return BasicTys[TyInt] }
+func (e *FloatLiteral) Type() *Ty       { return BasicTys[TyFloat] }
+func (e *BooleanLiteral) Type() *Ty     { return BasicTys[TyBool] }
+func (e *StringLiteral) Type() *Ty      { return BasicTys[TyString] }
+func (e *InterpExpr) Type() *Ty         { return BasicTys[TyString] }
+func (e *NilLiteral) Type() *Ty         { return BasicTys[TyNil] }
+func (e *UnaryExpr) Type() *Ty          { return e.Op.Ty }
+func (e *BinaryExpr) Type() *Ty         { return e.Op.Ty }
+func (e *LetExpr) Type() *Ty            { return BasicTys[TyUnit] }
+func (e *AssignExpr) Type() *Ty         { return BasicTys[TyUnit] }
+func (e *CompoundAssignExpr) Type() *Ty { return BasicTys[TyUnit] }
+func (e *FnExpr) Type() *Ty {
+	params := make([]*Ty, len(e.In))
+	for i, param := range e.In {
+		params[i] = param.Ty
+	}
+	return NewFn(params, e.Out)
+}
+func (e *MatchExpr) Type() *Ty { return e.Ty }
+func (e *IfExpr) Type() *Ty    { return e.Ty }
 func (e *BlockExpr) Type() *Ty {
 	if len(e.Exprs) == 0 {
 		return BasicTys[TyUnit]
 	}
 	return e.Exprs[len(e.Exprs)-1].Type()
 }
-func (e *CallExpr) Type() *Ty  { return e.Fn.Type() }
-func (e *ClassExpr) Type() *Ty { return NewClass(e.Ident) }
+func (e *CallExpr) Type() *Ty  { return e.Ty }
+func (e *ClassExpr) Type() *Ty { return NewClass(e.Ident, e.Args) }
 func (e *FieldExpr) Type() *Ty { return e.Ty }
 func (e *ArrayExpr) Type() *Ty {
 	if len(e.Exprs) == 0 {
@@ -204,22 +389,165 @@ func (e *ArrayExpr) Type() *Ty {
 	}
 	return NewArray(e.Exprs[0].Type())
 }
-func (e *IndexExpr) Type() *Ty { return e.Arr.(*VarDecl).Ty.Elem }
-func (e *ForExpr) Type() *Ty   { return e.Body.Type() }
+func (e *MapExpr) Type() *Ty {
+	if len(e.Entries) == 0 {
+		return NewMap(BasicTys[TyInfer], BasicTys[TyInfer])
+	}
+	return NewMap(e.Entries[0].Key.Type(), e.Entries[0].Val.Type())
+}
+func (e *MapIndexExpr) Type() *Ty { return e.Map.Type().Elem }
+func (e *TupleExpr) Type() *Ty {
+	elems := make([]*Ty, len(e.Exprs))
+	for i, x := range e.Exprs {
+		elems[i] = x.Type()
+	}
+	return NewTuple(elems)
+}
+func (e *TupleIndexExpr) Type() *Ty { return e.X.Type().Elems[e.I] }
+func (e *IndexExpr) Type() *Ty      { return e.Arr.Type().Elem }
+func (e *RangeExpr) Type() *Ty      { return BasicTys[TyRange] }
+func (e *ForExpr) Type() *Ty {
+	// A conditional or iterating loop may finish without reaching a `break`.
+	if e.Cond != nil || e.Iter != nil {
+		return BasicTys[TyUnit]
+	}
+	return e.Body.Type()
+}
 func (e *BreakExpr) Type() *Ty {
 	if e.X == nil {
 		return BasicTys[TyUnit]
 	}
 	return e.X.Type()
 }
+func (e *ContinueExpr) Type() *Ty { return BasicTys[TyUnit] }
 func (e *ReturnExpr) Type() *Ty {
 	if e.X == nil {
 		return BasicTys[TyUnit]
 	}
 	return e.X.Type()
 }
-func (e Intrinsic) Type() *Ty { return BasicTys[TyUnit] }
-func (e *ErrExpr) Type() *Ty  { return BasicTys[TyErr] }
+func (e Intrinsic) Type() *Ty {
+	switch ir.Intrinsic(e) {
+	case ir.IntrInt, ir.IntrLen:
+		return BasicTys[TyInt]
+	case ir.IntrFloat:
+		return BasicTys[TyFloat]
+	default:
+		return BasicTys[TyUnit]
+	}
+}
+func (e *ErrExpr) Type() *Ty { return BasicTys[TyErr] }
+
+// An arm of a match expression.
+// `pat [if guard] => body`
+type MatchArm struct {
+	Pat   Pat
+	Guard Expr // Optional, may be nil.
+	Body  Expr
+}
+
+type Pat interface {
+	isPat()
+}
+
+// Patterns
+type (
+	// A wildcard pattern.
+	// `_`
+	WildPat struct{}
+
+	// A binding pattern.
+	// E.g., `n`
+	BindPat struct {
+		Decl *VarDecl
+	}
+
+	// A literal pattern, X is an integer, string or boolean literal.
+	// E.g., `1`, `"foo"` or `true`
+	LitPat struct {
+		X Expr
+	}
+
+	// An integer range pattern.
+	// `lo..hi` or `lo..=hi`
+	RangePat struct {
+		Lo        int
+		Hi        int
+		Inclusive bool
+	}
+
+	// A class pattern.
+	// `ident { ident: pat, ... }`
+	ClassPat struct {
+		Class  *Class
+		Fields []*PatField
+	}
+
+	// A tuple pattern.
+	// `(pats)`
+	TuplePat struct {
+		Pats []Pat
+	}
+
+	// An enum variant pattern.
+	// `enum.variant[(pats)]`
+	VariantPat struct {
+		Enum    *Enum
+		Variant *Variant
+		Pats    []Pat
+	}
+)
+
+// `ident: pat`
+type PatField struct {
+	Ident *ir.Ident
+	Pat   Pat
+}
+
+// Ensure that we can only assign pattern nodes to a Pat.
+func (*WildPat) isPat()    {}
+func (*BindPat) isPat()    {}
+func (*LitPat) isPat()     {}
+func (*RangePat) isPat()   {}
+func (*ClassPat) isPat()   {}
+func (*TuplePat) isPat()   {}
+func (*VariantPat) isPat() {}
+
+// A variant of an enum.
+type Variant struct {
+	Ident *ir.Ident
+	Tys   []*Ty // The types of the payload, if any.
+}
+
+// Variant returns the variant of enum e named name, or nil if there is no such variant.
+func (e *Enum) Variant(name string) *Variant {
+	for _, v := range e.Variants {
+		if v.Ident.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Field returns the field of class c named name, or nil if there is no such field.
+func (c *Class) Field(name string) *VarDecl {
+	for _, f := range c.Fields {
+		if f.Ident.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Method returns the method of class c named name, or nil if there is no such method.
+func (c *Class) Method(name string) *Fn {
+	for _, m := range c.Methods {
+		if m.Decl.Ident.Name == name {
+			return m
+		}
+	}
+	return nil
+}
 
 type TyKind int
 
@@ -227,10 +555,19 @@ const (
 	TyErr TyKind = iota
 	TyInfer
 	TyInt
+	TyFloat
 	TyBool
 	TyString
 	TyArray
+	TyRange
 	TyClass
+	TyEnum
+	TyFn
+	TyParam
+	TyOptional
+	TyNil
+	TyTuple
+	TyMap
 	TyUnit
 )
 
@@ -238,15 +575,25 @@ var BasicTys = [...]*Ty{
 	TyErr:    {Kind: TyErr},
 	TyInfer:  {Kind: TyInfer},
 	TyInt:    {Kind: TyInt},
+	TyFloat:  {Kind: TyFloat},
 	TyBool:   {Kind: TyBool},
 	TyString: {Kind: TyString},
+	TyRange:  {Kind: TyRange},
+	TyNil:    {Kind: TyNil},
 	TyUnit:   {Kind: TyUnit},
 }
 
 type Ty struct {
-	Kind  TyKind
-	Elem  *Ty
-	Class *ir.Ident
+	Kind   TyKind
+	Elem   *Ty // Element type if kind is `TyArray` or `TyOptional`, value type if `TyMap`.
+	Key    *Ty // Key type if kind is `TyMap`.
+	Class  *ir.Ident
+	Args   []*Ty     // Type arguments if kind is `TyClass`.
+	Enum   *ir.Ident // Name if kind is `TyEnum`.
+	Params []*Ty     // Parameter types if kind is `TyFn`.
+	Out    *Ty       // Return type if kind is `TyFn`.
+	Elems  []*Ty     // Element types if kind is `TyTuple`.
+	Name   *ir.Ident // Name if kind is `TyParam`.
 }
 
 func (t *Ty) IsErr() bool {
@@ -261,6 +608,10 @@ func (t *Ty) IsInt() bool {
 	return t.Kind == TyInt
 }
 
+func (t *Ty) IsFloat() bool {
+	return t.Kind == TyFloat
+}
+
 func (t *Ty) IsBool() bool {
 	return t.Kind == TyBool
 }
@@ -277,26 +628,189 @@ func (t *Ty) IsArray() bool {
 	return t.Kind == TyArray
 }
 
+func (t *Ty) IsMap() bool {
+	return t.Kind == TyMap
+}
+
+func (t *Ty) IsRange() bool {
+	return t.Kind == TyRange
+}
+
 func (t *Ty) IsClass() bool {
 	return t.Kind == TyClass
 }
 
+func (t *Ty) IsEnum() bool {
+	return t.Kind == TyEnum
+}
+
+func (t *Ty) IsFn() bool {
+	return t.Kind == TyFn
+}
+
+func (t *Ty) IsParam() bool {
+	return t.Kind == TyParam
+}
+
+func (t *Ty) IsOptional() bool {
+	return t.Kind == TyOptional
+}
+
+func (t *Ty) IsNil() bool {
+	return t.Kind == TyNil
+}
+
+func (t *Ty) IsTuple() bool {
+	return t.Kind == TyTuple
+}
+
+// Equal reports whether types t and other are the same type.
+// Array, map and tuple elements, function types and type arguments are compared deeply, and classes and enums by name.
+// An inferred type in other, e.g., the element type of an empty array literal,
+// is equal to any type, but one in t is only equal to another inferred type.
 func (t *Ty) Equal(other *Ty) bool {
-	if t.Kind == other.Kind {
-		if t.Kind == TyArray {
-			return t.Elem.Kind == other.Elem.Kind
+	if other.IsInfer() {
+		return true
+	}
+	if t.Kind != other.Kind {
+		return false
+	}
+
+	switch t.Kind {
+	case TyArray, TyOptional:
+		return t.Elem.Equal(other.Elem)
+	case TyMap:
+		return t.Key.Equal(other.Key) && t.Elem.Equal(other.Elem)
+	case TyClass:
+		if t.Class.Name != other.Class.Name || len(t.Args) != len(other.Args) {
+			return false
+		}
+		for i, arg := range t.Args {
+			if !arg.Equal(other.Args[i]) {
+				return false
+			}
+		}
+		return true
+	case TyEnum:
+		return t.Enum.Name == other.Enum.Name
+	case TyParam:
+		// Every declared type parameter is a distinct type.
+		return t == other
+	case TyFn:
+		if len(t.Params) != len(other.Params) {
+			return false
+		}
+		for i, param := range t.Params {
+			if !param.Equal(other.Params[i]) {
+				return false
+			}
+		}
+		return t.Out.Equal(other.Out)
+	case TyTuple:
+		if len(t.Elems) != len(other.Elems) {
+			return false
+		}
+		for i, elem := range t.Elems {
+			if !elem.Equal(other.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// Accepts reports whether a value of type other can be stored where type t is expected.
+// That is the case if they are the same type, or if t is optional and other is `nil`,
+// the element type of t or an optional of it. Tuples accept each other element-wise.
+func (t *Ty) Accepts(other *Ty) bool {
+	if t.IsOptional() && !other.IsOptional() {
+		return other.IsNil() || t.Elem.Equal(other)
+	}
+	if t.IsTuple() && other.IsTuple() && len(t.Elems) == len(other.Elems) {
+		for i, elem := range t.Elems {
+			if !elem.Accepts(other.Elems[i]) {
+				return false
+			}
 		}
 		return true
 	}
-	return false
+	return t.Equal(other)
 }
 
 func NewArray(elem *Ty) *Ty {
 	return &Ty{Kind: TyArray, Elem: elem}
 }
 
-func NewClass(ident *ir.Ident) *Ty {
-	return &Ty{Kind: TyClass, Class: ident}
+func NewMap(key, val *Ty) *Ty {
+	return &Ty{Kind: TyMap, Key: key, Elem: val}
+}
+
+func NewClass(ident *ir.Ident, args []*Ty) *Ty {
+	return &Ty{Kind: TyClass, Class: ident, Args: args}
+}
+
+func NewEnum(ident *ir.Ident) *Ty {
+	return &Ty{Kind: TyEnum, Enum: ident}
+}
+
+func NewFn(params []*Ty, out *Ty) *Ty {
+	return &Ty{Kind: TyFn, Params: params, Out: out}
+}
+
+// NewOptional returns the optional type of elem.
+// Types that can already be `nil` are returned as they are, e.g., `int??` is `int?`.
+func NewOptional(elem *Ty) *Ty {
+	if elem.IsOptional() || elem.IsNil() || elem.IsErr() {
+		return elem
+	}
+	return &Ty{Kind: TyOptional, Elem: elem}
+}
+
+func NewTuple(elems []*Ty) *Ty {
+	return &Ty{Kind: TyTuple, Elems: elems}
+}
+
+func NewParam(name *ir.Ident) *Ty {
+	return &Ty{Kind: TyParam, Name: name}
+}
+
+// Subst returns type t with the type parameters in subst replaced by
+// the types they map to. Type parameters that map to nil are left as they are.
+func (t *Ty) Subst(subst map[*Ty]*Ty) *Ty {
+	switch t.Kind {
+	case TyParam:
+		if ty := subst[t]; ty != nil {
+			return ty
+		}
+		return t
+	case TyArray:
+		return NewArray(t.Elem.Subst(subst))
+	case TyOptional:
+		return NewOptional(t.Elem.Subst(subst))
+	case TyMap:
+		return NewMap(t.Key.Subst(subst), t.Elem.Subst(subst))
+	case TyClass:
+		if len(t.Args) == 0 {
+			return t
+		}
+		return NewClass(t.Class, substAll(t.Args, subst))
+	case TyFn:
+		return NewFn(substAll(t.Params, subst), t.Out.Subst(subst))
+	case TyTuple:
+		return NewTuple(substAll(t.Elems, subst))
+	default:
+		return t
+	}
+}
+
+func substAll(tys []*Ty, subst map[*Ty]*Ty) []*Ty {
+	out := make([]*Ty, len(tys))
+	for i, ty := range tys {
+		out[i] = ty.Subst(subst)
+	}
+	return out
 }
 
 func (t *Ty) String() string {
@@ -307,14 +821,61 @@ func (t *Ty) String() string {
 		return "?"
 	case TyInt:
 		return "int"
+	case TyFloat:
+		return "float"
 	case TyBool:
 		return "bool"
 	case TyString:
 		return "string"
 	case TyArray:
 		return "[" + t.Elem.String() + "]"
+	case TyMap:
+		return "{" + t.Key.String() + ": " + t.Elem.String() + "}"
+	case TyRange:
+		return "range"
 	case TyClass:
-		return t.Class.Name
+		if len(t.Args) == 0 {
+			return t.Class.Name
+		}
+		args := make([]string, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = arg.String()
+		}
+		return t.Class.Name + "<" + strings.Join(args, ", ") + ">"
+	case TyEnum:
+		return t.Enum.Name
+	case TyParam:
+		return t.Name.Name
+	case TyFn:
+		var builder strings.Builder
+		builder.WriteString("fn(")
+		for i, param := range t.Params {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteString(param.String())
+		}
+		builder.WriteByte(')')
+		if !t.Out.IsUnit() {
+			builder.WriteString(": " + t.Out.String())
+		}
+		return builder.String()
+	case TyOptional:
+		if t.Elem.IsFn() {
+			return "(" + t.Elem.String() + ")?"
+		}
+		return t.Elem.String() + "?"
+	case TyNil:
+		return "nil"
+	case TyTuple:
+		elems := make([]string, len(t.Elems))
+		for i, elem := range t.Elems {
+			elems[i] = elem.String()
+		}
+		if len(elems) == 1 {
+			return "(" + elems[0] + ",)"
+		}
+		return "(" + strings.Join(elems, ", ") + ")"
 	case TyUnit:
 		return "()"
 	default:
@@ -322,6 +883,37 @@ func (t *Ty) String() string {
 	}
 }
 
+type UnOp struct {
+	Kind UnOpKind
+	Ty   *Ty
+}
+
+var unOps = [...]struct {
+	in  ast.UnOpKind
+	xTy TyKind
+	out UnOp
+}{
+	{ast.Neg, TyInt, UnOp{Kind: Neg, Ty: BasicTys[TyInt]}},
+	{ast.Neg, TyFloat, UnOp{Kind: Neg, Ty: BasicTys[TyFloat]}},
+	{ast.Not, TyBool, UnOp{Kind: Not, Ty: BasicTys[TyBool]}},
+}
+
+func BindUnOp(astOp ast.UnOpKind, xTy TyKind) (UnOp, bool) {
+	for _, op := range unOps {
+		if op.in == astOp && op.xTy == xTy {
+			return op.out, true
+		}
+	}
+	return *new(UnOp), false
+}
+
+type UnOpKind int
+
+const (
+	Neg UnOpKind = iota // `-` (negation)
+	Not                 // `!` (logical not)
+)
+
 type BinOp struct {
 	Kind BinOpKind
 	Ty   *Ty
@@ -337,19 +929,41 @@ var binOps = [...]struct {
 	{ast.Sub, TyInt, TyInt, BinOp{Kind: Sub, Ty: BasicTys[TyInt]}},
 	{ast.Mul, TyInt, TyInt, BinOp{Kind: Mul, Ty: BasicTys[TyInt]}},
 	{ast.Div, TyInt, TyInt, BinOp{Kind: Div, Ty: BasicTys[TyInt]}},
+	{ast.Rem, TyInt, TyInt, BinOp{Kind: Rem, Ty: BasicTys[TyInt]}},
+
+	{ast.BitAnd, TyInt, TyInt, BinOp{Kind: BitAnd, Ty: BasicTys[TyInt]}},
+	{ast.BitOr, TyInt, TyInt, BinOp{Kind: BitOr, Ty: BasicTys[TyInt]}},
+	{ast.BitXor, TyInt, TyInt, BinOp{Kind: BitXor, Ty: BasicTys[TyInt]}},
+	{ast.Shl, TyInt, TyInt, BinOp{Kind: Shl, Ty: BasicTys[TyInt]}},
+	{ast.Shr, TyInt, TyInt, BinOp{Kind: Shr, Ty: BasicTys[TyInt]}},
+
+	{ast.Add, TyFloat, TyFloat, BinOp{Kind: Add, Ty: BasicTys[TyFloat]}},
+	{ast.Sub, TyFloat, TyFloat, BinOp{Kind: Sub, Ty: BasicTys[TyFloat]}},
+	{ast.Mul, TyFloat, TyFloat, BinOp{Kind: Mul, Ty: BasicTys[TyFloat]}},
+	{ast.Div, TyFloat, TyFloat, BinOp{Kind: Div, Ty: BasicTys[TyFloat]}},
 
 	{ast.Gt, TyInt, TyInt, BinOp{Kind: Gt, Ty: BasicTys[TyBool]}},
 	{ast.Lt, TyInt, TyInt, BinOp{Kind: Lt, Ty: BasicTys[TyBool]}},
 	{ast.Ge, TyInt, TyInt, BinOp{Kind: Ge, Ty: BasicTys[TyBool]}},
 	{ast.Le, TyInt, TyInt, BinOp{Kind: Le, Ty: BasicTys[TyBool]}},
 
+	{ast.Gt, TyFloat, TyFloat, BinOp{Kind: Gt, Ty: BasicTys[TyBool]}},
+	{ast.Lt, TyFloat, TyFloat, BinOp{Kind: Lt, Ty: BasicTys[TyBool]}},
+	{ast.Ge, TyFloat, TyFloat, BinOp{Kind: Ge, Ty: BasicTys[TyBool]}},
+	{ast.Le, TyFloat, TyFloat, BinOp{Kind: Le, Ty: BasicTys[TyBool]}},
+
 	{ast.Eq, TyInt, TyInt, BinOp{Kind: Eq, Ty: BasicTys[TyBool]}},
+	{ast.Eq, TyFloat, TyFloat, BinOp{Kind: Eq, Ty: BasicTys[TyBool]}},
 	{ast.Eq, TyBool, TyBool, BinOp{Kind: Eq, Ty: BasicTys[TyBool]}},
 	{ast.Eq, TyString, TyString, BinOp{Kind: Eq, Ty: BasicTys[TyBool]}},
 
 	{ast.Ne, TyInt, TyInt, BinOp{Kind: Ne, Ty: BasicTys[TyBool]}},
+	{ast.Ne, TyFloat, TyFloat, BinOp{Kind: Ne, Ty: BasicTys[TyBool]}},
 	{ast.Ne, TyBool, TyBool, BinOp{Kind: Ne, Ty: BasicTys[TyBool]}},
 	{ast.Ne, TyString, TyString, BinOp{Kind: Ne, Ty: BasicTys[TyBool]}},
+
+	{ast.And, TyBool, TyBool, BinOp{Kind: And, Ty: BasicTys[TyBool]}},
+	{ast.Or, TyBool, TyBool, BinOp{Kind: Or, Ty: BasicTys[TyBool]}},
 }
 
 func BindBinOp(astOp ast.BinOpKind, xTy, yTy TyKind) (BinOp, bool) {
@@ -364,14 +978,23 @@ func BindBinOp(astOp ast.BinOpKind, xTy, yTy TyKind) (BinOp, bool) {
 type BinOpKind int
 
 const (
-	Add BinOpKind = iota // `+` (addition)
-	Sub                  // `-` (subtraction)
-	Mul                  // `*` (multiplication)
-	Div                  // `/` (division)
-	Gt                   // `>` (greater than)
-	Lt                   // `<` (less than)
-	Ge                   // `>=` (greater than or equal)
-	Le                   // `<=` (less than or equal)
-	Eq                   // `==` (equality)
-	Ne                   // `!=` (not equal)
+	Add    BinOpKind = iota // `+` (addition)
+	Sub                     // `-` (subtraction)
+	Mul                     // `*` (multiplication)
+	Div                     // `/` (division)
+	Rem                     // `%` (remainder)
+	BitAnd                  // `&` (bitwise and)
+	BitOr                   // `|` (bitwise or)
+	BitXor                  // `^` (bitwise xor)
+	Shl                     // `<<` (shift left)
+	Shr                     // `>>` (shift right)
+	Gt                      // `>` (greater than)
+	Lt                      // `<` (less than)
+	Ge                      // `>=` (greater than or equal)
+	Le                      // `<=` (less than or equal)
+	Eq                      // `==` (equality)
+	Ne                      // `!=` (not equal)
+	And                     // `&&` (logical and)
+	Or                      // `||` (logical or)
+	In                      // `in` (map key presence)
 )
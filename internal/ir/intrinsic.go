@@ -6,19 +6,25 @@ type Intrinsic int
 
 const (
 	IntrPrintln Intrinsic = iota
+	IntrInt
+	IntrFloat
+	IntrLen
 )
 
 func Intrinsics() []Intrinsic {
-	return []Intrinsic{IntrPrintln}
+	return []Intrinsic{IntrPrintln, IntrInt, IntrFloat, IntrLen}
 }
 
 var intrinsics = [...]string{
 	IntrPrintln: "println",
+	IntrInt:     "int",
+	IntrFloat:   "float",
+	IntrLen:     "len",
 }
 
 func (i Intrinsic) String() string {
-	if i < 0 || i > Intrinsic(len(intrinsics)) {
+	if i < 0 || i >= Intrinsic(len(intrinsics)) {
 		return "Intrinsic(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return intrinsics[IntrPrintln]
+	return intrinsics[i]
 }
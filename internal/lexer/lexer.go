@@ -2,7 +2,10 @@ package lexer
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/aadamandersson/lue/internal/diagnostic"
 	"github.com/aadamandersson/lue/internal/session"
@@ -16,8 +19,10 @@ func Lex(sess *session.Session) []token.Token {
 }
 
 type lexer struct {
-	sess *session.Session
-	pos  int // Current position in src.
+	sess    *session.Session
+	pos     int        // Current position in src.
+	prev    token.Kind // Kind of the previous token.
+	interps []int      // Brace depth within each string interpolation being lexed, innermost last.
 }
 
 func new(sess *session.Session) *lexer {
@@ -27,89 +32,174 @@ func new(sess *session.Session) *lexer {
 func (l *lexer) Lex() []token.Token {
 	var tokens []token.Token
 	for {
-		b := l.peek()
+		r := l.peek()
 
-		if b == 0 {
+		if r == 0 {
 			break
 		}
 
-		if isWhitespace(b) {
+		if isWhitespace(r) {
 			l.eatWhile(isWhitespace)
 			continue
 		}
 
 		start := l.pos
 		l.next()
-		if b == '/' && l.peek() == '/' {
-			l.eatWhile(func(b byte) bool { return b != '\n' })
+		if r == '/' && l.peek() == '/' {
+			l.eatWhile(func(r rune) bool { return r != '\n' })
 			continue
 		}
-		kind, lit := l.lexToken(b)
+		kind, lit := l.lexToken(r)
 		tokens = append(tokens, token.New(kind, lit, span.New(start, l.pos)))
+		l.prev = kind
 	}
 
 	tokens = append(tokens, token.New(token.Eof, "", span.NewEmpty(l.pos)))
 	return tokens
 }
 
-func (l *lexer) lexToken(first byte) (token.Kind, string) {
+func (l *lexer) lexToken(first rune) (token.Kind, string) {
 	peek := l.peek()
 	switch first {
 	case '+':
+		if peek == '=' {
+			l.next()
+			return token.PlusEq, ""
+		}
 		return token.Plus, ""
 	case '-':
+		if peek == '=' {
+			l.next()
+			return token.MinusEq, ""
+		}
 		return token.Minus, ""
 	case '*':
+		if peek == '=' {
+			l.next()
+			return token.StarEq, ""
+		}
 		return token.Star, ""
 	case '/':
+		if peek == '=' {
+			l.next()
+			return token.SlashEq, ""
+		}
 		return token.Slash, ""
+	case '%':
+		return token.Percent, ""
+	case '^':
+		return token.Caret, ""
 	case '>':
 		if peek == '=' {
 			l.next()
 			return token.Ge, ""
 		}
+		if peek == '>' {
+			l.next()
+			return token.Shr, ""
+		}
 		return token.Gt, ""
 	case '<':
 		if peek == '=' {
 			l.next()
 			return token.Le, ""
 		}
+		if peek == '<' {
+			l.next()
+			return token.Shl, ""
+		}
 		return token.Lt, ""
 	case '=':
 		if peek == '=' {
 			l.next()
 			return token.EqEq, ""
 		}
+		if peek == '>' {
+			l.next()
+			return token.FatArrow, ""
+		}
 		return token.Eq, ""
 	case '!':
 		if peek == '=' {
 			l.next()
 			return token.Ne, ""
 		}
-		return token.Unknown, string(first)
+		return token.Bang, ""
+	case '&':
+		if peek == '&' {
+			l.next()
+			return token.AmpAmp, ""
+		}
+		return token.Amp, ""
+	case '|':
+		if peek == '|' {
+			l.next()
+			return token.PipePipe, ""
+		}
+		return token.Pipe, ""
 	case ':':
 		return token.Colon, ""
 	case ',':
 		return token.Comma, ""
+	case '?':
+		return token.Question, ""
 	case '.':
+		if peek == '.' {
+			l.next()
+			if l.peek() == '=' {
+				l.next()
+				return token.DotDotEq, ""
+			}
+			return token.DotDot, ""
+		}
 		return token.Dot, ""
 	case '(':
 		return token.LParen, ""
 	case '[':
 		return token.LBrack, ""
 	case '{':
+		if n := len(l.interps); n > 0 {
+			l.interps[n-1]++
+		}
 		return token.LBrace, ""
 	case ')':
 		return token.RParen, ""
 	case ']':
 		return token.RBrack, ""
 	case '}':
+		// A `}` that is not matched within an interpolation ends it.
+		if n := len(l.interps); n > 0 {
+			if l.interps[n-1] == 0 {
+				l.interps = l.interps[:n-1]
+				return l.lexString(true)
+			}
+			l.interps[n-1]--
+		}
 		return token.RBrace, ""
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		// A number after `.` is a tuple index, so that `t.0.1` is not lexed as `t` `.` `0.1`.
+		if l.prev == token.Dot {
+			return token.Number, l.collectString(first, isDigit)
+		}
 		return l.lexNumeric(first)
 	case '"':
-		return l.lexString()
+		if peek == '"' && l.lookahead(1) == '"' {
+			l.next()
+			l.next()
+			return l.lexMultiLineString()
+		}
+		return l.lexString(false)
+	case '\'':
+		if isIdentStart(peek) {
+			l.next()
+			_, s := l.lexIdent(peek)
+			return token.Label, "'" + s
+		}
+		return token.Unknown, string(first)
 	default:
+		if first == 'r' && l.isRawStringStart() {
+			return l.lexRawString()
+		}
 		if isIdentStart(first) {
 			return l.lexIdent(first)
 		}
@@ -118,118 +208,408 @@ func (l *lexer) lexToken(first byte) (token.Kind, string) {
 }
 
 // lexNumeric lexes a number and returns its kind and literal value.
-func (l *lexer) lexNumeric(first byte) (token.Kind, string) {
-	s := l.collectString(first, isDigit)
-	return token.Number, s
+// A number with a fractional part or an exponent, e.g., `1.5` or `1e3`, is a float.
+func (l *lexer) lexNumeric(first rune) (token.Kind, string) {
+	kind := token.Number
+	var builder strings.Builder
+	builder.WriteString(l.collectString(first, isDigit))
+
+	if l.peek() == '.' && isDigit(l.lookahead(1)) {
+		kind = token.Float
+		builder.WriteByte('.')
+		l.next()
+		l.collectInto(&builder, isDigit)
+	}
+
+	if r := l.peek(); r == 'e' || r == 'E' {
+		n := 1
+		if sign := l.lookahead(1); sign == '+' || sign == '-' {
+			n = 2
+		}
+		if isDigit(l.lookahead(n)) {
+			kind = token.Float
+			for i := 0; i < n; i++ {
+				builder.WriteRune(l.peek())
+				l.next()
+			}
+			l.collectInto(&builder, isDigit)
+		}
+	}
+
+	return kind, builder.String()
 }
 
-// lexString lexes a string and returns its kind and literal value.
-// `"` already eaten.
-func (l *lexer) lexString() (token.Kind, string) {
+// lexString lexes a string, or the part of one up to an interpolated expression,
+// and returns its kind and literal value. A `{` starts an interpolated expression,
+// which is lexed as ordinary tokens up to the matching `}`, after which the rest
+// of the string is lexed with cont set to true.
+// `"` or `}` already eaten.
+func (l *lexer) lexString(cont bool) (token.Kind, string) {
+	kind := token.String
+	if cont {
+		kind = token.StringTail
+	}
+
 	var builder strings.Builder
 loop:
 	for {
-		b := l.peek()
-		switch b {
+		r := l.peek()
+		switch r {
 		case 0, '\r', '\n':
-			sp := span.NewEmpty(l.pos)
-			diagnostic.NewBuilder("unterminated string", sp).WithLabel("expected `\"` here").Emit(l.sess.Diags)
+			l.error(span.NewEmpty(l.pos), "expected `\"` here", "unterminated string")
 			break loop
-		case '\\':
+		case '{':
 			l.next()
-			escByte := l.peek()
-			switch escByte {
-			case '"', '\\':
-				builder.WriteByte(escByte)
-				l.next()
-			default:
-				sp := span.NewEmpty(l.pos)
-				msg := fmt.Sprintf("unknown character escape `%s`", string(escByte))
-				diagnostic.NewBuilder(msg, sp).WithLabel("unknown character escape here").Emit(l.sess.Diags)
+			l.interps = append(l.interps, 0)
+			if cont {
+				return token.StringMid, builder.String()
 			}
+			return token.StringHead, builder.String()
+		case '\\':
+			l.lexEscape(&builder)
 		case '"':
 			l.next()
 			break loop
 		default:
 			l.next()
-			builder.WriteByte(b)
+			builder.WriteRune(r)
 		}
 	}
 
+	return kind, builder.String()
+}
+
+// isRawStringStart reports whether the lexer is at the `"` or `#` following
+// the `r` of a raw string.
+func (l *lexer) isRawStringStart() bool {
+	n := 0
+	for l.lookahead(n) == '#' {
+		n++
+	}
+	return l.lookahead(n) == '"'
+}
+
+// lexRawString lexes a raw string, whose content is taken verbatim and may span
+// several lines, and returns its kind and literal value. The opening `"` may be
+// preceded by any number of `#`, in which case the closing `"` must be followed
+// by as many, so that the content can contain `"`, e.g., `r#"say "hi""#`.
+// `r` already eaten.
+func (l *lexer) lexRawString() (token.Kind, string) {
+	hashes := 0
+	for l.peek() == '#' {
+		hashes++
+		l.next()
+	}
+	l.next()
+
+	src := l.sess.File.Src
+	start := l.pos
+	closing := "\"" + strings.Repeat("#", hashes)
+	end := strings.Index(string(src[start:]), closing)
+	if end < 0 {
+		l.pos = len(src)
+		l.error(span.NewEmpty(l.pos), fmt.Sprintf("expected `%s` here", closing), "unterminated raw string")
+		return token.String, string(src[start:])
+	}
+
+	l.pos = start + end + len(closing)
+	return token.String, string(src[start : start+end])
+}
+
+// lexMultiLineString lexes a string delimited by `"""` and returns its kind and
+// literal value. Its content starts on the line after the opening `"""` and ends
+// before the closing one, or on the line before it if the closing `"""` is on a
+// line of its own. The indentation common to its non-blank lines and to the closing
+// `"""` is stripped from every line, so that it can be indented along with the code.
+// Escapes are processed as in other strings, but a `{` does not start an interpolation.
+// `"""` already eaten.
+func (l *lexer) lexMultiLineString() (token.Kind, string) {
+	openSp := span.New(l.pos-3, l.pos)
+	l.eatWhile(isBlank)
+	if l.peek() == '\r' {
+		l.next()
+	}
+	if l.peek() != '\n' {
+		l.error(span.NewEmpty(l.pos), "expected a line break here", "multi-line string must start on a new line after `\"\"\"`")
+		l.eatWhile(func(r rune) bool { return r != '\n' })
+	}
+	l.next()
+
+	src := l.sess.File.Src
+	start, end := l.pos, l.pos
+	for end < len(src) && !strings.HasPrefix(string(src[end:]), "\"\"\"") {
+		if src[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(src) {
+		end = len(src)
+		l.error(openSp, "multi-line string starts here", "unterminated multi-line string")
+	}
+
+	lines := strings.Split(string(src[start:end]), "\n")
+	closingLine := strings.TrimLeft(lines[len(lines)-1], " \t") == ""
+	indent := -1
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.TrimRight(trimmed, "\r") == "" && !(closingLine && i == len(lines)-1) {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if closingLine {
+		lines = lines[:len(lines)-1]
+	}
+	if indent < 0 {
+		indent = 0
+	}
+
+	var builder strings.Builder
+	lineStart := start
+	for i, line := range lines {
+		if i > 0 {
+			builder.WriteByte('\n')
+		}
+		content := strings.TrimRight(line, "\r")
+		if strings.TrimLeft(content, " \t") == "" {
+			// Blank lines may be less indented than the others, or not at all.
+			lineStart += len(line) + 1
+			continue
+		}
+
+		lineEnd := lineStart + len(content)
+		l.pos = lineStart + indent
+		for l.pos < lineEnd {
+			if r := l.peek(); r == '\\' {
+				l.lexEscape(&builder)
+			} else {
+				builder.WriteRune(r)
+				l.next()
+			}
+		}
+		lineStart += len(line) + 1
+	}
+
+	l.pos = end
+	if end < len(src) {
+		l.pos += 3
+	}
 	return token.String, builder.String()
 }
 
+// lexEscape lexes an escape sequence and writes the character it denotes into builder.
+// Nothing is written for an invalid escape sequence, which is reported instead.
+// `\` not yet eaten.
+func (l *lexer) lexEscape(builder *strings.Builder) {
+	start := l.pos
+	l.next()
+	escByte := l.peek()
+	switch escByte {
+	case '"', '\\', '{', '}':
+		builder.WriteRune(escByte)
+	case 'n':
+		builder.WriteByte('\n')
+	case 't':
+		builder.WriteByte('\t')
+	case 'r':
+		builder.WriteByte('\r')
+	case '0':
+		builder.WriteByte(0)
+	case 'x':
+		l.next()
+		l.lexHexEscape(builder, start)
+		return
+	case 'u':
+		l.next()
+		l.lexUnicodeEscape(builder, start)
+		return
+	default:
+		l.error(span.NewEmpty(l.pos), "unknown character escape here", "unknown character escape `%s`", string(escByte))
+		return
+	}
+	l.next()
+}
+
+// lexHexEscape lexes the two hex digits of `\xNN`, which must denote an ASCII character.
+// `\x` already eaten, starting at start.
+func (l *lexer) lexHexEscape(builder *strings.Builder, start int) {
+	digits := 0
+	for digits < 2 && isHexDigit(l.peek()) {
+		digits++
+		l.next()
+	}
+
+	sp := span.New(start, l.pos)
+	if digits < 2 {
+		l.error(sp, "expected two hex digits", "numeric character escape is too short")
+		return
+	}
+
+	v, _ := strconv.ParseUint(string(l.sess.File.Src[start+2:l.pos]), 16, 8)
+	if v > 0x7f {
+		l.error(sp, "must be at most `\\x7f`", "out of range hex escape")
+		return
+	}
+	builder.WriteByte(byte(v))
+}
+
+// lexUnicodeEscape lexes the `{...}` of `\u{...}`, which must contain one to six
+// hex digits that denote a Unicode scalar value, and writes it encoded as UTF-8.
+// `\u` already eaten, starting at start.
+func (l *lexer) lexUnicodeEscape(builder *strings.Builder, start int) {
+	if l.peek() != '{' {
+		l.error(span.New(start, l.pos), "expected `{` after `\\u`", "incorrect unicode escape sequence")
+		return
+	}
+	l.next()
+
+	digitsStart := l.pos
+	l.eatWhile(isHexDigit)
+	digits := string(l.sess.File.Src[digitsStart:l.pos])
+
+	switch r := l.peek(); {
+	case r == '}':
+		l.next()
+	case r == '"' || r == 0 || r == '\r' || r == '\n':
+		l.error(span.New(start, l.pos), "expected `}` here", "unterminated unicode escape")
+		return
+	default:
+		l.error(span.New(l.pos, l.pos+utf8.RuneLen(r)), "invalid character here", "invalid character `%c` in unicode escape", r)
+		return
+	}
+
+	sp := span.New(start, l.pos)
+	if len(digits) == 0 {
+		l.error(sp, "expected at least one hex digit", "empty unicode escape")
+		return
+	}
+	if len(digits) > 6 {
+		l.error(sp, "must have at most 6 hex digits", "overlong unicode escape")
+		return
+	}
+
+	v, _ := strconv.ParseUint(digits, 16, 32)
+	switch {
+	case v > unicode.MaxRune:
+		l.error(sp, "must be at most `10FFFF`", "invalid unicode character escape")
+		return
+	case v >= 0xd800 && v <= 0xdfff:
+		l.error(sp, "unicode escape must not be a surrogate", "invalid unicode character escape")
+		return
+	}
+	builder.WriteRune(rune(v))
+}
+
 // lexIdent lexes an identifier and returns its kind and literal value.
-func (l *lexer) lexIdent(first byte) (token.Kind, string) {
+func (l *lexer) lexIdent(first rune) (token.Kind, string) {
 	s := l.collectString(first, isIdentCont)
 	return token.Lookup(s), s
 }
 
-// collectString collects bytes into a string while matches returns true and
+// collectString collects runes into a string while matches returns true and
 // the lexer is not at EOF.
-func (l *lexer) collectString(first byte, matches func(byte) bool) string {
+func (l *lexer) collectString(first rune, matches func(rune) bool) string {
 	var builder strings.Builder
-	builder.WriteByte(first)
+	builder.WriteRune(first)
+	l.collectInto(&builder, matches)
+	return builder.String()
+}
 
+// collectInto writes runes into builder while matches returns true and
+// the lexer is not at EOF.
+func (l *lexer) collectInto(builder *strings.Builder, matches func(rune) bool) {
 	for {
-		b := l.peek()
-		if b == 0 || !matches(b) {
+		r := l.peek()
+		if r == 0 || !matches(r) {
 			break
 		}
-		builder.WriteByte(b)
+		builder.WriteRune(r)
 		l.next()
 	}
-
-	return builder.String()
 }
 
-// peek returns the next byte without advancing the lexer.
+// peek returns the next rune without advancing the lexer.
 //
 // If the lexer is at EOF, 0 is returned.
-func (l *lexer) peek() byte {
-	if l.pos < len(l.sess.File.Src) {
-		return l.sess.File.Src[l.pos]
+func (l *lexer) peek() rune {
+	return l.lookahead(0)
+}
+
+// lookahead returns the rune n runes after the current one without advancing the lexer.
+// Invalid UTF-8 is decoded as `utf8.RuneError`, one byte at a time.
+//
+// If that position is at or past EOF, 0 is returned.
+func (l *lexer) lookahead(n int) rune {
+	src := l.sess.File.Src
+	pos := l.pos
+	for ; n > 0 && pos < len(src); n-- {
+		_, size := utf8.DecodeRune(src[pos:])
+		pos += size
+	}
+	if pos < len(src) {
+		r, _ := utf8.DecodeRune(src[pos:])
+		return r
 	}
 	return 0
 }
 
-// next advances the lexer to the next byte in src.
+// next advances the lexer to the next rune in src.
 func (l *lexer) next() {
 	if l.pos < len(l.sess.File.Src) {
-		l.pos += 1
+		_, size := utf8.DecodeRune(l.sess.File.Src[l.pos:])
+		l.pos += size
 	}
 }
 
-// eatWhile eats bytes while matches returns true and the lexer is not at EOF.
-func (l *lexer) eatWhile(matches func(byte) bool) {
+// eatWhile eats runes while matches returns true and the lexer is not at EOF.
+func (l *lexer) eatWhile(matches func(rune) bool) {
 	for matches(l.peek()) && !l.isEof() {
 		l.next()
 	}
 }
 
+// error reports an error with message format at span sp, labeled with label.
+func (l *lexer) error(sp span.Span, label, format string, a ...any) {
+	msg := fmt.Sprintf(format, a...)
+	diagnostic.NewBuilder(msg, sp).WithLabel(label).Emit(l.sess.Diags)
+}
+
 // isEof returns true if the lexer is at EOF, otherwise false.
 func (l *lexer) isEof() bool {
 	return l.pos == len(l.sess.File.Src)
 }
 
-// isDigit returns true if byte b is a digit, otherwise false.
-func isDigit(b byte) bool {
-	return b >= '0' && b <= '9'
+// isDigit returns true if rune r is an ASCII digit, otherwise false.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isHexDigit returns true if rune r is a hexadecimal digit, otherwise false.
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+// isIdentStart returns true if rune r is valid as a first character
+// of an identifier, that is, a letter or `_`, otherwise false.
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
 }
 
-// isIdentStart returns true if byte b is valid as a first character
-// of an identifier, otherwise false.
-func isIdentStart(b byte) bool {
-	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b == '_'
+// isIdentCont returns true if rune r is valid as a non-first character
+// of an identifier, which may also be a digit or a combining mark, otherwise false.
+func isIdentCont(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc)
 }
 
-// isIdentCont returns true if byte b is valid as a non-first character
-// of an identifier, otherwise false.
-func isIdentCont(b byte) bool {
-	return isIdentStart(b) || isDigit(b)
+// isBlank returns true if rune r is a space or a tab, otherwise false.
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t'
 }
 
-// isWhitespace returns true if byte b is whitespace, otherwise false.
-func isWhitespace(b byte) bool {
-	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+// isWhitespace returns true if rune r is whitespace, otherwise false.
+func isWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
 }
@@ -3,6 +3,7 @@ package lexer
 import (
 	"testing"
 
+	"github.com/aadamandersson/lue/internal/diagnostic"
 	"github.com/aadamandersson/lue/internal/session"
 	"github.com/aadamandersson/lue/internal/span"
 	"github.com/aadamandersson/lue/internal/token"
@@ -22,23 +23,65 @@ var cases = []struct {
 	{"foo", token.New(token.Ident, "foo", span.New(0, 3))},
 	{"_foo", token.New(token.Ident, "_foo", span.New(0, 4))},
 	{"foo123", token.New(token.Ident, "foo123", span.New(0, 6))},
+	{"größe", token.New(token.Ident, "größe", span.New(0, 7))},
+	{"日本", token.New(token.Ident, "日本", span.New(0, 6))},
+	{"é1", token.New(token.Ident, "é1", span.New(0, 3))},
+	{"€", token.New(token.Unknown, "€", span.New(0, 3))},
 	{"123", token.New(token.Number, "123", span.New(0, 3))},
+	{"1.5", token.New(token.Float, "1.5", span.New(0, 3))},
+	{"1e3", token.New(token.Float, "1e3", span.New(0, 3))},
+	{"2.5E-3", token.New(token.Float, "2.5E-3", span.New(0, 6))},
+	{"1.x", token.New(token.Number, "1", span.New(0, 1))},
+	{"1else", token.New(token.Number, "1", span.New(0, 1))},
 	{`"foo"`, token.New(token.String, "foo", span.New(0, 5))},
+	{"'outer", token.New(token.Label, "'outer", span.New(0, 6))},
+	{"'for", token.New(token.Label, "'for", span.New(0, 4))},
 	{`"foo\"bar\""`, token.New(token.String, `foo"bar"`, span.New(0, 12))},
+	{`"\{x\}"`, token.New(token.String, "{x}", span.New(0, 7))},
+	{`"\n\t\r\0"`, token.New(token.String, "\n\t\r\x00", span.New(0, 10))},
+	{`"\x41\x7f"`, token.New(token.String, "A\x7f", span.New(0, 10))},
+	{`"\u{e9}\u{1F600}"`, token.New(token.String, "é😀", span.New(0, 17))},
+	{`r"a\n{b}"`, token.New(token.String, `a\n{b}`, span.New(0, 9))},
+	{"r\"a\nb\"", token.New(token.String, "a\nb", span.New(0, 6))},
+	{`r#"say "hi""#`, token.New(token.String, `say "hi"`, span.New(0, 13))},
+	{`r##"a"#b"##`, token.New(token.String, `a"#b`, span.New(0, 11))},
+	{"\"\"\"\n  a\n    b\n\n  c\n  \"\"\"", token.New(token.String, "a\n  b\n\nc", span.New(0, 24))},
+	{"\"\"\"\n    a\\tb\n  \"\"\"", token.New(token.String, "  a\tb", span.New(0, 18))},
+	{"\"\"\"\r\n  a\r\n  b\"\"\"", token.New(token.String, "a\nb", span.New(0, 16))},
+	{"\"\"\"\n\"\"\"", token.New(token.String, "", span.New(0, 7))},
+	{`"a{x}"`, token.New(token.StringHead, "a", span.New(0, 3))},
 	{"+", token.New(token.Plus, "", span.New(0, 1))},
 	{"-", token.New(token.Minus, "", span.New(0, 1))},
 	{"*", token.New(token.Star, "", span.New(0, 1))},
 	{"/", token.New(token.Slash, "", span.New(0, 1))},
+	{"%", token.New(token.Percent, "", span.New(0, 1))},
+	{"&", token.New(token.Amp, "", span.New(0, 1))},
+	{"|", token.New(token.Pipe, "", span.New(0, 1))},
+	{"^", token.New(token.Caret, "", span.New(0, 1))},
+	{"<<", token.New(token.Shl, "", span.New(0, 2))},
+	{">>", token.New(token.Shr, "", span.New(0, 2))},
+	{"!", token.New(token.Bang, "", span.New(0, 1))},
 	{"=", token.New(token.Eq, "", span.New(0, 1))},
 	{">", token.New(token.Gt, "", span.New(0, 1))},
 	{"<", token.New(token.Lt, "", span.New(0, 1))},
 	{">=", token.New(token.Ge, "", span.New(0, 2))},
 	{"<=", token.New(token.Le, "", span.New(0, 2))},
 	{"==", token.New(token.EqEq, "", span.New(0, 2))},
+	{"=>", token.New(token.FatArrow, "", span.New(0, 2))},
 	{"!=", token.New(token.Ne, "", span.New(0, 2))},
+	{"&&", token.New(token.AmpAmp, "", span.New(0, 2))},
+	{"||", token.New(token.PipePipe, "", span.New(0, 2))},
+	{"+=", token.New(token.PlusEq, "", span.New(0, 2))},
+	{"-=", token.New(token.MinusEq, "", span.New(0, 2))},
+	{"*=", token.New(token.StarEq, "", span.New(0, 2))},
+	{"/=", token.New(token.SlashEq, "", span.New(0, 2))},
 	{":", token.New(token.Colon, "", span.New(0, 1))},
+	{"?", token.New(token.Question, "", span.New(0, 1))},
 	{",", token.New(token.Comma, "", span.New(0, 1))},
 	{".", token.New(token.Dot, "", span.New(0, 1))},
+	{"..", token.New(token.DotDot, "", span.New(0, 2))},
+	{"..=", token.New(token.DotDotEq, "", span.New(0, 3))},
+	{"0..5", token.New(token.Number, "0", span.New(0, 1))},
 	{"(", token.New(token.LParen, "", span.New(0, 1))},
 	{"[", token.New(token.LBrack, "", span.New(0, 1))},
 	{"{", token.New(token.LBrace, "", span.New(0, 1))},
@@ -47,16 +90,33 @@ var cases = []struct {
 	{"}", token.New(token.RBrace, "", span.New(0, 1))},
 	{"class", token.New(token.Class, "class", span.New(0, 5))},
 	{"break", token.New(token.Break, "break", span.New(0, 5))},
+	{"continue", token.New(token.Continue, "continue", span.New(0, 8))},
 	{"else", token.New(token.Else, "else", span.New(0, 4))},
+	{"enum", token.New(token.Enum, "enum", span.New(0, 4))},
 	{"false", token.New(token.False, "false", span.New(0, 5))},
 	{"fn", token.New(token.Fn, "fn", span.New(0, 2))},
 	{"for", token.New(token.For, "for", span.New(0, 3))},
 	{"if", token.New(token.If, "if", span.New(0, 2))},
+	{"in", token.New(token.In, "in", span.New(0, 2))},
 	{"let", token.New(token.Let, "let", span.New(0, 3))},
+	{"match", token.New(token.Match, "match", span.New(0, 5))},
+	{"nil", token.New(token.Nil, "nil", span.New(0, 3))},
 	{"return", token.New(token.Return, "return", span.New(0, 6))},
+	{"self", token.New(token.Self, "self", span.New(0, 4))},
 	{"true", token.New(token.True, "true", span.New(0, 4))},
 }
 
+// The tokens of `"a{x}b{ {} }c"`, where `{}` is lexed as braces within an interpolation.
+var interpTokens = []token.Token{
+	token.New(token.StringHead, "a", span.New(0, 3)),
+	token.New(token.Ident, "x", span.New(3, 4)),
+	token.New(token.StringMid, "b", span.New(4, 7)),
+	token.New(token.LBrace, "", span.New(8, 9)),
+	token.New(token.RBrace, "", span.New(9, 10)),
+	token.New(token.StringTail, "c", span.New(11, 14)),
+	token.New(token.Eof, "", span.NewEmpty(14)),
+}
+
 func TestLex(t *testing.T) {
 	for _, c := range cases {
 		got := lex(c.in)[0]
@@ -76,12 +136,89 @@ func TestLexerLexesAllTokens(t *testing.T) {
 			}
 		}
 
+		for _, tok := range interpTokens {
+			if k == tok.Kind {
+				found = true
+				break
+			}
+		}
+
 		if !found {
 			t.Errorf("Token `%s` is not handled by the lexer.\n", k.String())
 		}
 	}
 }
 
+func TestLexTupleIndex(t *testing.T) {
+	want := []token.Token{
+		token.New(token.Ident, "t", span.New(0, 1)),
+		token.New(token.Dot, "", span.New(1, 2)),
+		token.New(token.Number, "0", span.New(2, 3)),
+		token.New(token.Dot, "", span.New(3, 4)),
+		token.New(token.Number, "1", span.New(4, 5)),
+		token.New(token.Eof, "", span.NewEmpty(5)),
+	}
+
+	got := lex("t.0.1")
+	if len(got) != len(want) {
+		t.Fatalf("Lex(\"t.0.1\") = %+v, want %+v\n", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Lex(\"t.0.1\")[%d] = %+v, want %+v\n", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLexInterp(t *testing.T) {
+	src := `"a{x}b{ {} }c"`
+	got := lex(src)
+	if len(got) != len(interpTokens) {
+		t.Fatalf("Lex(%q) = %+v, want %+v\n", src, got, interpTokens)
+	}
+	for i := range interpTokens {
+		if got[i] != interpTokens[i] {
+			t.Errorf("Lex(%q)[%d] = %+v, want %+v\n", src, i, got[i], interpTokens[i])
+		}
+	}
+}
+
+func TestLexStringErrors(t *testing.T) {
+	cases := []struct {
+		in   string
+		msg  string
+		want span.Span
+	}{
+		{`"\q"`, "unknown character escape `q`", span.NewEmpty(2)},
+		{`"\x4"`, "numeric character escape is too short", span.New(1, 4)},
+		{`"\xg0"`, "numeric character escape is too short", span.New(1, 3)},
+		{`"\x80"`, "out of range hex escape", span.New(1, 5)},
+		{`"\u41"`, "incorrect unicode escape sequence", span.New(1, 3)},
+		{`"\u{}"`, "empty unicode escape", span.New(1, 5)},
+		{`"\u{41"`, "unterminated unicode escape", span.New(1, 6)},
+		{`"\u{4x}"`, "invalid character `x` in unicode escape", span.New(5, 6)},
+		{`"\u{1000000}"`, "overlong unicode escape", span.New(1, 12)},
+		{`"\u{110000}"`, "invalid unicode character escape", span.New(1, 11)},
+		{`"\u{d800}"`, "invalid unicode character escape", span.New(1, 9)},
+		{`r#"a"`, "unterminated raw string", span.NewEmpty(5)},
+		{`"""a"""`, "multi-line string must start on a new line after `\"\"\"`", span.NewEmpty(3)},
+		{"\"\"\"\na", "unterminated multi-line string", span.New(0, 3)},
+	}
+
+	for _, c := range cases {
+		sess := session.New("test", []byte(c.in))
+		Lex(sess)
+		found := false
+		sess.Diags.ForEach(func(d *diagnostic.Diagnostic) bool {
+			found = d.Msg == c.msg && d.Span == c.want
+			return true
+		})
+		if !found {
+			t.Errorf("Lex(%s) did not report %q at %+v\n", c.in, c.msg, c.want)
+		}
+	}
+}
+
 func lex(src string) []token.Token {
 	sess := session.New("test", []byte(src))
 	return Lex(sess)
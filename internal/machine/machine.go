@@ -1,17 +1,32 @@
 package machine
 
 import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/aadamandersson/lue/internal/binder"
+	"github.com/aadamandersson/lue/internal/diagnostic"
 	"github.com/aadamandersson/lue/internal/ir"
 	"github.com/aadamandersson/lue/internal/ir/bir"
 	"github.com/aadamandersson/lue/internal/parser"
 	"github.com/aadamandersson/lue/internal/session"
+	"github.com/aadamandersson/lue/internal/span"
 )
 
 func Interpret(filename string, src []byte, kernel Kernel) bool {
 	sess := session.New(filename, src)
 	aItems := parser.Parse(sess)
 	classes, fns := binder.Bind(aItems, sess)
+	if !sess.Diags.Empty() {
+		sess.DumpDiags()
+		if sess.Diags.HasErrors() {
+			return false
+		}
+		// Only warnings, which have been reported now.
+		sess.Diags = diagnostic.NewBag()
+	}
+
 	m := newMachine(classes, fns, sess, kernel)
 	ok := m.interpret()
 
@@ -54,14 +69,26 @@ func (s *stack) peek() *frame {
 
 type frame struct {
 	locals map[*bir.VarDecl]Value
+	outer  *frame // The frame a closure was created in, if any.
 }
 
-func newFrame(locals map[*bir.VarDecl]Value) *frame {
-	return &frame{locals: locals}
+func newFrame(locals map[*bir.VarDecl]Value, outer *frame) *frame {
+	return &frame{locals: locals, outer: outer}
 }
 
 func (f *frame) local(decl *bir.VarDecl) Value {
-	return f.locals[decl]
+	return f.owner(decl).locals[decl]
+}
+
+// owner returns the frame that holds the variable decl. Variables captured by
+// a closure are held by one of the outer frames, all others by frame f itself.
+func (f *frame) owner(decl *bir.VarDecl) *frame {
+	for o := f; o != nil; o = o.outer {
+		if _, ok := o.locals[decl]; ok {
+			return o
+		}
+	}
+	return f
 }
 
 type machine struct {
@@ -93,7 +120,7 @@ func (m *machine) interpret() bool {
 		m.kernel.Println("no `main` function found")
 		return false
 	}
-	m.stack.push(newFrame(map[*bir.VarDecl]Value{}))
+	m.stack.push(newFrame(map[*bir.VarDecl]Value{}, nil))
 	_, ok = m.evalExpr(main.Body)
 	return ok
 }
@@ -101,22 +128,41 @@ func (m *machine) interpret() bool {
 func (m *machine) evalExpr(expr bir.Expr) (Value, bool) {
 	switch expr := expr.(type) {
 	case *bir.Fn:
-		fn := m.fns[expr.Decl.Ident.Name]
-		return &Fn{Params: fn.In, Body: fn.Body}, true
+		return &Fn{Params: expr.In, Body: expr.Body}, true
+	case *bir.MethodExpr:
+		return m.evalMethodExpr(expr)
+	case *bir.FnExpr:
+		return m.evalFnExpr(expr)
+	case *bir.VariantExpr:
+		return m.evalVariantExpr(expr)
 	case *bir.VarDecl:
 		return m.stack.peek().local(expr), true
+	case *bir.Narrowed:
+		return m.stack.peek().local(expr.Decl), true
 	case *bir.IntegerLiteral:
 		return Integer(expr.V), true
+	case *bir.FloatLiteral:
+		return Float(expr.V), true
 	case *bir.BooleanLiteral:
 		return Boolean(expr.V), true
 	case *bir.StringLiteral:
 		return String(expr.V), true
+	case *bir.InterpExpr:
+		return m.evalInterpExpr(expr)
+	case *bir.NilLiteral:
+		return Nil{}, true
+	case *bir.UnaryExpr:
+		return m.evalUnaryExpr(expr)
 	case *bir.BinaryExpr:
 		return m.evalBinaryExpr(expr)
 	case *bir.LetExpr:
 		return m.evalLetExpr(expr)
 	case *bir.AssignExpr:
 		return m.evalAssignExpr(expr)
+	case *bir.CompoundAssignExpr:
+		return m.evalCompoundAssignExpr(expr)
+	case *bir.MatchExpr:
+		return m.evalMatchExpr(expr)
 	case *bir.IfExpr:
 		return m.evalIfExpr(expr)
 	case *bir.BlockExpr:
@@ -129,12 +175,24 @@ func (m *machine) evalExpr(expr bir.Expr) (Value, bool) {
 		return m.evalFieldExpr(expr)
 	case *bir.ArrayExpr:
 		return m.evalArrayExpr(expr)
+	case *bir.MapExpr:
+		return m.evalMapExpr(expr)
+	case *bir.MapIndexExpr:
+		return m.evalMapIndexExpr(expr)
+	case *bir.TupleExpr:
+		return m.evalTupleExpr(expr)
+	case *bir.TupleIndexExpr:
+		return m.evalTupleIndexExpr(expr)
 	case *bir.IndexExpr:
 		return m.evalIndexExpr(expr)
+	case *bir.RangeExpr:
+		return m.evalRangeExpr(expr)
 	case *bir.ForExpr:
 		return m.evalForExpr(expr)
 	case *bir.BreakExpr:
 		return m.evalBreakExpr(expr)
+	case *bir.ContinueExpr:
+		return &ContinueVal{Loop: expr.Loop}, true
 	case *bir.ReturnExpr:
 		return m.evalReturnExpr(expr)
 	case bir.Intrinsic:
@@ -146,21 +204,164 @@ func (m *machine) evalExpr(expr bir.Expr) (Value, bool) {
 	panic("unreachable")
 }
 
+func (m *machine) evalInterpExpr(expr *bir.InterpExpr) (Value, bool) {
+	var builder strings.Builder
+	for _, part := range expr.Parts {
+		v, ok := m.evalExpr(part)
+		if !ok || unwinds(v) {
+			return v, ok
+		}
+		builder.WriteString(v.String())
+	}
+	return String(builder.String()), true
+}
+
+func (m *machine) evalUnaryExpr(expr *bir.UnaryExpr) (Value, bool) {
+	x, ok := m.evalExpr(expr.X)
+	if !ok || unwinds(x) {
+		return x, ok
+	}
+
+	switch x := x.(type) {
+	case Integer:
+		switch expr.Op.Kind {
+		case bir.Neg:
+			return -x, true
+		}
+	case Float:
+		switch expr.Op.Kind {
+		case bir.Neg:
+			return -x, true
+		}
+	case Boolean:
+		switch expr.Op.Kind {
+		case bir.Not:
+			return !x, true
+		}
+	}
+	panic("unreachable")
+}
+
 func (m *machine) evalBinaryExpr(expr *bir.BinaryExpr) (Value, bool) {
 	x, ok := m.evalExpr(expr.X)
-	if !ok {
-		return nil, ok
+	if !ok || unwinds(x) {
+		return x, ok
+	}
+
+	// The right operand of a logical operator is only evaluated if
+	// the left one does not decide the result.
+	switch expr.Op.Kind {
+	case bir.And:
+		if !x.(Boolean) {
+			return x, true
+		}
+		return m.evalExpr(expr.Y)
+	case bir.Or:
+		if x.(Boolean) {
+			return x, true
+		}
+		return m.evalExpr(expr.Y)
 	}
 
 	y, ok := m.evalExpr(expr.Y)
-	if !ok {
-		return nil, ok
+	if !ok || unwinds(y) {
+		return y, ok
+	}
+
+	return m.binOp(expr.Op, x, y, expr.Sp)
+}
+
+// binOp applies the arithmetic or comparison operator op to x and y.
+// Span sp is used to report runtime errors, such as division by zero.
+func (m *machine) binOp(op bir.BinOp, x, y Value, sp span.Span) (Value, bool) {
+	// Optional values are equal if both are `nil`, or if neither is and what they hold is.
+	// No other operator applies to `nil`, which the binder should have ruled out.
+	_, xNil := x.(Nil)
+	_, yNil := y.(Nil)
+	if xNil || yNil {
+		if op.Kind != bir.Eq && op.Kind != bir.Ne {
+			m.error(sp, "attempt to use `nil` as an operand")
+			return nil, false
+		}
+		return Boolean((xNil && yNil) == (op.Kind == bir.Eq)), true
+	}
+
+	if op.Kind == bir.In {
+		_, ok := y.(*Map).Get(x)
+		return Boolean(ok), true
 	}
 
 	switch x := x.(type) {
+	case *Tuple:
+		// Tuples are equal if all of their elements are.
+		y := y.(*Tuple)
+		eq := true
+		for i, elem := range x.Elems {
+			v, ok := m.binOp(bir.BinOp{Kind: bir.Eq, Ty: op.Ty}, elem, y.Elems[i], sp)
+			if !ok {
+				return nil, ok
+			}
+			if !v.(Boolean) {
+				eq = false
+				break
+			}
+		}
+		return Boolean(eq == (op.Kind == bir.Eq)), true
 	case Integer:
 		y := y.(Integer)
-		switch expr.Op.Kind {
+		switch op.Kind {
+		case bir.Add:
+			return x + y, true
+		case bir.Sub:
+			return x - y, true
+		case bir.Mul:
+			return x * y, true
+		case bir.Div:
+			if y == 0 {
+				m.error(sp, "attempt to divide by zero")
+				return nil, false
+			}
+			return x / y, true
+		case bir.Rem:
+			if y == 0 {
+				m.error(sp, "attempt to calculate the remainder with a divisor of zero")
+				return nil, false
+			}
+			return x % y, true
+		case bir.BitAnd:
+			return x & y, true
+		case bir.BitOr:
+			return x | y, true
+		case bir.BitXor:
+			return x ^ y, true
+		case bir.Shl:
+			if y < 0 {
+				m.error(sp, "attempt to shift left by a negative amount")
+				return nil, false
+			}
+			return x << y, true
+		case bir.Shr:
+			if y < 0 {
+				m.error(sp, "attempt to shift right by a negative amount")
+				return nil, false
+			}
+			return x >> y, true
+		case bir.Gt:
+			return Boolean(x > y), true
+		case bir.Lt:
+			return Boolean(x < y), true
+		case bir.Ge:
+			return Boolean(x >= y), true
+		case bir.Le:
+			return Boolean(x <= y), true
+		case bir.Eq:
+			return Boolean(x == y), true
+		case bir.Ne:
+			return Boolean(x != y), true
+		}
+	case Float:
+		y := y.(Float)
+		switch op.Kind {
 		case bir.Add:
 			return x + y, true
 		case bir.Sub:
@@ -184,7 +385,7 @@ func (m *machine) evalBinaryExpr(expr *bir.BinaryExpr) (Value, bool) {
 		}
 	case Boolean:
 		y := y.(Boolean)
-		switch expr.Op.Kind {
+		switch op.Kind {
 		case bir.Eq:
 			return Boolean(x == y), true
 		case bir.Ne:
@@ -192,7 +393,7 @@ func (m *machine) evalBinaryExpr(expr *bir.BinaryExpr) (Value, bool) {
 		}
 	case String:
 		y := y.(String)
-		switch expr.Op.Kind {
+		switch op.Kind {
 		case bir.Eq:
 			return Boolean(x == y), true
 		case bir.Ne:
@@ -204,8 +405,13 @@ func (m *machine) evalBinaryExpr(expr *bir.BinaryExpr) (Value, bool) {
 
 func (m *machine) evalLetExpr(expr *bir.LetExpr) (Value, bool) {
 	v, ok := m.evalExpr(expr.Init)
-	if !ok {
-		return nil, ok
+	if !ok || unwinds(v) {
+		return v, ok
+	}
+	if expr.Pat != nil {
+		// The binder has checked that the pattern matches every value.
+		matches(expr.Pat, v, m.stack.peek().locals)
+		return Unit{}, true
 	}
 	m.stack.peek().locals[expr.Decl] = v
 	return Unit{}, true
@@ -213,20 +419,180 @@ func (m *machine) evalLetExpr(expr *bir.LetExpr) (Value, bool) {
 
 func (m *machine) evalAssignExpr(expr *bir.AssignExpr) (Value, bool) {
 	v, ok := m.evalExpr(expr.Y)
+	if !ok || unwinds(v) {
+		return v, ok
+	}
+
+	p, u, ok := m.evalPlace(expr.X)
+	if !ok || u != nil {
+		return u, ok
+	}
+	p.store(v)
+	return Unit{}, true
+}
+
+func (m *machine) evalCompoundAssignExpr(expr *bir.CompoundAssignExpr) (Value, bool) {
+	y, ok := m.evalExpr(expr.Y)
+	if !ok || unwinds(y) {
+		return y, ok
+	}
+
+	// The target is evaluated only once, so that e.g. `xs[f()] += 1`
+	// calls `f` a single time.
+	p, u, ok := m.evalPlace(expr.X)
+	if !ok || u != nil {
+		return u, ok
+	}
+
+	x, ok := p.load()
+	if !ok {
+		return nil, ok
+	}
+
+	v, ok := m.binOp(expr.Op, x, y, expr.Sp)
 	if !ok {
 		return nil, ok
 	}
-	// TODO: we ensure in the binder that this will always be an Ident for now,
-	// but eventually we want to support more types.
-	decl := expr.X.(*bir.VarDecl)
-	m.stack.peek().locals[decl] = v
+	p.store(v)
 	return Unit{}, true
 }
 
+// A place is an evaluated assignment target.
+type place struct {
+	load  func() (Value, bool)
+	store func(Value)
+}
+
+// evalPlace evaluates the receiver, the array and index or the map and key of expr,
+// and returns a place that reads from and writes to the resulting location.
+// If one of them unwinds, its value is returned instead of a place.
+func (m *machine) evalPlace(expr bir.Expr) (place, Value, bool) {
+	switch expr := expr.(type) {
+	case *bir.Narrowed:
+		return m.evalPlace(expr.Decl)
+	case *bir.VarDecl:
+		locals := m.stack.peek().owner(expr).locals
+		return place{
+			load:  func() (Value, bool) { return locals[expr], true },
+			store: func(v Value) { locals[expr] = v },
+		}, nil, true
+	case *bir.FieldExpr:
+		recv, ok := m.evalExpr(expr.Expr)
+		if !ok || unwinds(recv) {
+			return place{}, recv, ok
+		}
+		ins := recv.(*Instance)
+		return place{
+			load:  func() (Value, bool) { return ins.Get(expr.Ident), true },
+			store: func(v Value) { ins.Fields[expr.Ident.Name] = v },
+		}, nil, true
+	case *bir.IndexExpr:
+		arr, i, u, ok := m.evalIndex(expr)
+		if !ok || u != nil {
+			return place{}, u, ok
+		}
+		return place{
+			load:  func() (Value, bool) { return arr.Elems[i], true },
+			store: func(v Value) { arr.Elems[i] = v },
+		}, nil, true
+	case *bir.MapIndexExpr:
+		mv, key, u, ok := m.evalMapKey(expr)
+		if !ok || u != nil {
+			return place{}, u, ok
+		}
+		// Loading a missing key fails, but storing to it inserts it.
+		return place{
+			load:  func() (Value, bool) { return m.mapGet(mv, key, expr.Sp) },
+			store: func(v Value) { mv.Set(key, v) },
+		}, nil, true
+	}
+	panic(fmt.Sprintf("unexpected assignment target %T", expr))
+}
+
+// evalMatchExpr evaluates the body of the first arm whose pattern matches
+// and whose guard, if any, holds. The binder ensures that there is one.
+func (m *machine) evalMatchExpr(expr *bir.MatchExpr) (Value, bool) {
+	x, ok := m.evalExpr(expr.X)
+	if !ok || unwinds(x) {
+		return x, ok
+	}
+
+	locals := m.stack.peek().locals
+	for _, arm := range expr.Arms {
+		if !matches(arm.Pat, x, locals) {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard, ok := m.evalExpr(arm.Guard)
+			if !ok || unwinds(guard) {
+				return guard, ok
+			}
+			if !guard.(Boolean) {
+				continue
+			}
+		}
+		return m.evalExpr(arm.Body)
+	}
+	panic("unreachable")
+}
+
+// matches reports whether value v matches pattern pat, and binds
+// the variables of pat in locals.
+func matches(pat bir.Pat, v Value, locals map[*bir.VarDecl]Value) bool {
+	switch pat := pat.(type) {
+	case *bir.WildPat:
+		return true
+	case *bir.BindPat:
+		locals[pat.Decl] = v
+		return true
+	case *bir.LitPat:
+		switch x := pat.X.(type) {
+		case *bir.IntegerLiteral:
+			return v.(Integer) == Integer(x.V)
+		case *bir.StringLiteral:
+			return v.(String) == String(x.V)
+		case *bir.BooleanLiteral:
+			return v.(Boolean) == Boolean(x.V)
+		}
+	case *bir.RangePat:
+		i := int(v.(Integer))
+		return pat.Lo <= i && (i < pat.Hi || (pat.Inclusive && i == pat.Hi))
+	case *bir.ClassPat:
+		ins := v.(*Instance)
+		for _, f := range pat.Fields {
+			if !matches(f.Pat, ins.Get(f.Ident), locals) {
+				return false
+			}
+		}
+		return true
+	case *bir.TuplePat:
+		tuple := v.(*Tuple)
+		for i, sub := range pat.Pats {
+			if !matches(sub, tuple.Elems[i], locals) {
+				return false
+			}
+		}
+		return true
+	case *bir.VariantPat:
+		variant := v.(*Variant)
+		if variant.Name != pat.Variant.Ident.Name {
+			return false
+		}
+		for i, sub := range pat.Pats {
+			if !matches(sub, variant.Fields[i], locals) {
+				return false
+			}
+		}
+		return true
+	}
+	panic("unreachable")
+}
+
 func (m *machine) evalIfExpr(expr *bir.IfExpr) (Value, bool) {
 	cond, ok := m.evalExpr(expr.Cond)
-	if !ok {
-		return nil, ok
+	if !ok || unwinds(cond) {
+		return cond, ok
 	}
 
 	if cond.(Boolean) {
@@ -257,12 +623,8 @@ func (m *machine) evalBlockExpr(block *bir.BlockExpr) (Value, bool) {
 			return nil, ok
 		}
 
-		if rv, ok := value.(*RetVal); ok {
-			return rv.V, ok
-		}
-
-		if bv, ok := value.(*BreakVal); ok {
-			return bv, ok
+		if unwinds(value) {
+			return value, true
 		}
 
 		lastVal = value
@@ -272,8 +634,8 @@ func (m *machine) evalBlockExpr(block *bir.BlockExpr) (Value, bool) {
 
 func (m *machine) evalCallExpr(expr *bir.CallExpr) (Value, bool) {
 	fnVal, ok := m.evalExpr(expr.Fn)
-	if !ok {
-		return nil, ok
+	if !ok || unwinds(fnVal) {
+		return fnVal, ok
 	}
 
 	switch fn := fnVal.(type) {
@@ -281,66 +643,161 @@ func (m *machine) evalCallExpr(expr *bir.CallExpr) (Value, bool) {
 		switch fn {
 		case Intrinsic(ir.IntrPrintln):
 			arg, ok := m.evalExpr(expr.Args[0])
-			if !ok {
-				return nil, ok
+			if !ok || unwinds(arg) {
+				return arg, ok
 			}
 			m.kernel.Println(arg.String())
 			return Unit{}, true
+		case Intrinsic(ir.IntrInt):
+			arg, ok := m.evalExpr(expr.Args[0])
+			if !ok || unwinds(arg) {
+				return arg, ok
+			}
+			switch arg := arg.(type) {
+			case Integer:
+				return arg, true
+			case Float:
+				return Integer(arg), true
+			}
+		case Intrinsic(ir.IntrFloat):
+			arg, ok := m.evalExpr(expr.Args[0])
+			if !ok || unwinds(arg) {
+				return arg, ok
+			}
+			switch arg := arg.(type) {
+			case Integer:
+				return Float(arg), true
+			case Float:
+				return arg, true
+			}
+		case Intrinsic(ir.IntrLen):
+			arg, ok := m.evalExpr(expr.Args[0])
+			if !ok || unwinds(arg) {
+				return arg, ok
+			}
+			switch arg := arg.(type) {
+			case *Array:
+				return Integer(len(arg.Elems)), true
+			case *Map:
+				return Integer(len(arg.Keys)), true
+			case String:
+				return Integer(utf8.RuneCountInString(string(arg))), true
+			}
 		}
 	case *Fn:
-		if expr.Args == nil {
-			return m.evalExpr(fn.Body)
+		args, u, ok := m.evalArgs(expr.Args)
+		if !ok || u != nil {
+			return u, ok
 		}
-
-		locals := make(map[*bir.VarDecl]Value, len(expr.Args))
-		for i, arg := range expr.Args {
-			argVal, ok := m.evalExpr(arg)
-			if !ok {
-				return nil, ok
-			}
-			param := fn.Params[i]
-			locals[param] = argVal
+		return m.call(fn, args)
+	case *Method:
+		args, u, ok := m.evalArgs(expr.Args)
+		if !ok || u != nil {
+			return u, ok
 		}
-		m.stack.push(newFrame(locals))
-		v, ok := m.evalExpr(fn.Body)
-		m.stack.pop()
-		if !ok {
-			return nil, ok
+		return m.call(fn.Fn, append([]Value{fn.Recv}, args...))
+	}
+
+	panic("unreachable")
+}
+
+// evalArgs evaluates exprs in order. If one of them unwinds, the rest are
+// not evaluated and its value is returned in place of the values of exprs.
+func (m *machine) evalArgs(exprs []bir.Expr) ([]Value, Value, bool) {
+	args := make([]Value, 0, len(exprs))
+	for _, expr := range exprs {
+		v, ok := m.evalExpr(expr)
+		if !ok || unwinds(v) {
+			return nil, v, ok
 		}
+		args = append(args, v)
+	}
+	return args, nil, true
+}
 
-		return v, true
+// call calls function fn with args in a new frame.
+func (m *machine) call(fn *Fn, args []Value) (Value, bool) {
+	locals := make(map[*bir.VarDecl]Value, len(args))
+	for i, arg := range args {
+		locals[fn.Params[i]] = arg
 	}
 
-	panic("unreachable")
+	m.stack.push(newFrame(locals, fn.Env))
+	v, ok := m.evalExpr(fn.Body)
+	m.stack.pop()
+	if !ok {
+		return nil, ok
+	}
+
+	if rv, ok := v.(*RetVal); ok {
+		return rv.V, true
+	}
+	return v, true
 }
 
 func (m *machine) evalClassExpr(expr *bir.ClassExpr) (Value, bool) {
 	fields := make(map[string]Value, len(expr.Fields))
 	for _, f := range expr.Fields {
-		if v, ok := m.evalExpr(f.Expr); ok {
-			fields[f.Ident.Name] = v
-		} else {
-			return nil, false
+		v, ok := m.evalExpr(f.Expr)
+		if !ok || unwinds(v) {
+			return v, ok
 		}
+		fields[f.Ident.Name] = v
+	}
+	var names []string
+	for _, f := range m.classes[expr.Ident.Name].Fields {
+		names = append(names, f.Ident.Name)
 	}
-	return &Instance{Ident: expr.Ident, Fields: fields}, true
+	return &Instance{Ident: expr.Ident, Fields: fields, Names: names}, true
 }
 
 func (m *machine) evalFieldExpr(expr *bir.FieldExpr) (Value, bool) {
 	v, ok := m.evalExpr(expr.Expr)
-	if !ok {
-		return nil, ok
+	if !ok || unwinds(v) {
+		return v, ok
 	}
 	return v.(*Instance).Get(expr.Ident), true
 }
 
+// evalFnExpr creates a closure. If it captures any variables, it keeps the
+// current frame alive, so that the variables are shared with the enclosing function.
+func (m *machine) evalFnExpr(expr *bir.FnExpr) (Value, bool) {
+	fn := &Fn{Params: expr.In, Body: expr.Body}
+	if len(expr.Captures) > 0 {
+		fn.Env = m.stack.peek()
+	}
+	return fn, true
+}
+
+// evalMethodExpr binds the method to its receiver. The method is looked up
+// on the class of the receiver instance.
+func (m *machine) evalMethodExpr(expr *bir.MethodExpr) (Value, bool) {
+	v, ok := m.evalExpr(expr.Recv)
+	if !ok || unwinds(v) {
+		return v, ok
+	}
+
+	recv := v.(*Instance)
+	method := m.classes[recv.Ident.Name].Method(expr.Fn.Decl.Ident.Name)
+	return &Method{Recv: recv, Fn: &Fn{Params: method.In, Body: method.Body}}, true
+}
+
+func (m *machine) evalVariantExpr(expr *bir.VariantExpr) (Value, bool) {
+	fields, u, ok := m.evalArgs(expr.Args)
+	if !ok || u != nil {
+		return u, ok
+	}
+	enum := (*ir.Ident)(expr.Enum.Decl.Ident)
+	return &Variant{Enum: enum, Name: expr.Variant.Ident.Name, Fields: fields}, true
+}
+
 func (m *machine) evalArrayExpr(expr *bir.ArrayExpr) (Value, bool) {
 	var elems []Value
 
 	for _, expr := range expr.Exprs {
 		v, ok := m.evalExpr(expr)
-		if !ok {
-			return nil, false
+		if !ok || unwinds(v) {
+			return v, ok
 		}
 		elems = append(elems, v)
 	}
@@ -348,53 +805,251 @@ func (m *machine) evalArrayExpr(expr *bir.ArrayExpr) (Value, bool) {
 	return &Array{Elems: elems}, true
 }
 
-func (m *machine) evalIndexExpr(expr *bir.IndexExpr) (Value, bool) {
-	arrExpr, ok := m.evalExpr(expr.Arr)
-	if !ok {
-		return nil, ok
+func (m *machine) evalMapExpr(expr *bir.MapExpr) (Value, bool) {
+	mv := NewMap()
+	for _, entry := range expr.Entries {
+		key, ok := m.evalExpr(entry.Key)
+		if !ok || unwinds(key) {
+			return key, ok
+		}
+		val, ok := m.evalExpr(entry.Val)
+		if !ok || unwinds(val) {
+			return val, ok
+		}
+		mv.Set(key, val)
+	}
+	return mv, true
+}
+
+func (m *machine) evalMapIndexExpr(expr *bir.MapIndexExpr) (Value, bool) {
+	mv, key, u, ok := m.evalMapKey(expr)
+	if !ok || u != nil {
+		return u, ok
 	}
+	return m.mapGet(mv, key, expr.Sp)
+}
+
+// evalMapKey evaluates the map and key of expr.
+// If one of them unwinds, its value is returned instead.
+func (m *machine) evalMapKey(expr *bir.MapIndexExpr) (*Map, Value, Value, bool) {
+	vs, u, ok := m.evalArgs([]bir.Expr{expr.Map, expr.Key})
+	if !ok || u != nil {
+		return nil, nil, u, ok
+	}
+	return vs[0].(*Map), vs[1], nil, true
+}
 
-	idxExpr, ok := m.evalExpr(expr.I)
+// mapGet returns the value of key in map mv, and reports
+// an error at span sp if it is not present.
+func (m *machine) mapGet(mv *Map, key Value, sp span.Span) (Value, bool) {
+	v, ok := mv.Get(key)
 	if !ok {
-		return nil, ok
+		if s, isString := key.(String); isString {
+			m.error(sp, "key not found: %q", string(s))
+		} else {
+			m.error(sp, "key not found: %s", key)
+		}
+		return nil, false
+	}
+	return v, true
+}
+
+func (m *machine) evalTupleExpr(expr *bir.TupleExpr) (Value, bool) {
+	elems, u, ok := m.evalArgs(expr.Exprs)
+	if !ok || u != nil {
+		return u, ok
+	}
+	return &Tuple{Elems: elems}, true
+}
+
+func (m *machine) evalTupleIndexExpr(expr *bir.TupleIndexExpr) (Value, bool) {
+	v, ok := m.evalExpr(expr.X)
+	if !ok || unwinds(v) {
+		return v, ok
+	}
+	return v.(*Tuple).Elems[expr.I], true
+}
+
+func (m *machine) evalIndexExpr(expr *bir.IndexExpr) (Value, bool) {
+	arr, i, u, ok := m.evalIndex(expr)
+	if !ok || u != nil {
+		return u, ok
 	}
-	// TODO: out of bounds handling
-	arr := arrExpr.(*Array)
-	i := idxExpr.(Integer)
 	return arr.Elems[i], true
 }
 
+// evalIndex evaluates the array and index of expr and checks that
+// the index is within the bounds of the array.
+// If one of them unwinds, its value is returned instead.
+func (m *machine) evalIndex(expr *bir.IndexExpr) (*Array, int, Value, bool) {
+	vs, u, ok := m.evalArgs([]bir.Expr{expr.Arr, expr.I})
+	if !ok || u != nil {
+		return nil, 0, u, ok
+	}
+
+	arr := vs[0].(*Array)
+	i := int(vs[1].(Integer))
+	if i < 0 || i >= len(arr.Elems) {
+		m.error(expr.Sp, "index out of bounds: the length is %d but the index is %d", len(arr.Elems), i)
+		return nil, 0, nil, false
+	}
+	return arr, i, nil, true
+}
+
+func (m *machine) evalRangeExpr(expr *bir.RangeExpr) (Value, bool) {
+	bounds, u, ok := m.evalArgs([]bir.Expr{expr.Lo, expr.Hi})
+	if !ok || u != nil {
+		return u, ok
+	}
+
+	return &Range{Lo: bounds[0].(Integer), Hi: bounds[1].(Integer), Inclusive: expr.Inclusive}, true
+}
+
 func (m *machine) evalForExpr(expr *bir.ForExpr) (Value, bool) {
+	if expr.Iter != nil {
+		return m.evalForInExpr(expr)
+	}
+
 	for {
+		if expr.Cond != nil {
+			cond, ok := m.evalExpr(expr.Cond)
+			if !ok || unwinds(cond) {
+				return cond, ok
+			}
+			if !cond.(Boolean) {
+				return Unit{}, true
+			}
+		}
+
 		v, ok := m.evalExpr(expr.Body)
 		if !ok {
 			return nil, ok
 		}
 
-		if bv, ok := v.(*BreakVal); ok {
-			return bv.V, true
+		if res, stop := loopControl(expr, v); stop {
+			return res, true
 		}
 	}
 }
 
+func (m *machine) evalForInExpr(expr *bir.ForExpr) (Value, bool) {
+	iter, ok := m.evalExpr(expr.Iter)
+	if !ok || unwinds(iter) {
+		return iter, ok
+	}
+
+	locals := m.stack.peek().locals
+	switch iter := iter.(type) {
+	case *Array:
+		for i := 0; i < len(iter.Elems); i++ {
+			locals[expr.Var] = iter.Elems[i]
+			v, ok := m.evalExpr(expr.Body)
+			if !ok {
+				return nil, ok
+			}
+
+			if res, stop := loopControl(expr, v); stop {
+				return res, true
+			}
+		}
+	case *Map:
+		// Keys inserted by the body are not visited.
+		keys := append([]Value(nil), iter.Keys...)
+		for _, key := range keys {
+			locals[expr.Var] = key
+			if expr.Val != nil {
+				locals[expr.Val] = iter.Vals[key]
+			}
+			v, ok := m.evalExpr(expr.Body)
+			if !ok {
+				return nil, ok
+			}
+
+			if res, stop := loopControl(expr, v); stop {
+				return res, true
+			}
+		}
+	case *Range:
+		// Stop explicitly at `iter.Hi`, so that an inclusive range ending at
+		// the largest integer does not overflow.
+		for i := iter.Lo; i < iter.Hi || (iter.Inclusive && i == iter.Hi); i++ {
+			locals[expr.Var] = i
+			v, ok := m.evalExpr(expr.Body)
+			if !ok {
+				return nil, ok
+			}
+
+			if res, stop := loopControl(expr, v); stop {
+				return res, true
+			}
+
+			if i == iter.Hi {
+				break
+			}
+		}
+	default:
+		panic("unreachable")
+	}
+
+	return Unit{}, true
+}
+
 func (m *machine) evalBreakExpr(expr *bir.BreakExpr) (Value, bool) {
 	if expr.X != nil {
 		v, ok := m.evalExpr(expr.X)
-		if !ok {
-			return nil, ok
+		if !ok || unwinds(v) {
+			return v, ok
 		}
-		return &BreakVal{V: v}, true
+		return &BreakVal{V: v, Loop: expr.Loop}, true
 	}
-	return &BreakVal{V: Unit{}}, true
+	return &BreakVal{V: Unit{}, Loop: expr.Loop}, true
 }
 
 func (m *machine) evalReturnExpr(expr *bir.ReturnExpr) (Value, bool) {
 	if expr.X != nil {
 		v, ok := m.evalExpr(expr.X)
-		if !ok {
-			return nil, ok
+		if !ok || unwinds(v) {
+			return v, ok
 		}
 		return &RetVal{V: v}, true
 	}
 	return &RetVal{V: Unit{}}, true
 }
+
+// loopControl handles the value v of an iteration of the body of loop expr.
+// It returns the value that loop expr should return and a boolean true, if the loop should stop.
+// Otherwise, returns nil and a boolean false.
+func loopControl(expr *bir.ForExpr, v Value) (Value, bool) {
+	switch v := v.(type) {
+	case *BreakVal:
+		if v.Loop == expr {
+			return v.V, true
+		}
+		return v, true
+	case *ContinueVal:
+		if v.Loop == expr {
+			return nil, false
+		}
+		return v, true
+	case *RetVal:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// unwinds returns true if value v is the result of a `return`, `break` or `continue`
+// expression, which should skip the rest of the enclosing blocks, otherwise false.
+func unwinds(v Value) bool {
+	switch v.(type) {
+	case *RetVal, *BreakVal, *ContinueVal:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *machine) error(span span.Span, format string, a ...any) {
+	msg := fmt.Sprintf(format, a...)
+	diagnostic.NewBuilder(msg, span).WithLabel("here").Emit(m.sess.Diags)
+}
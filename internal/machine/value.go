@@ -2,6 +2,7 @@ package machine
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/aadamandersson/lue/internal/ir"
@@ -15,44 +16,92 @@ type Value interface {
 
 type (
 	Integer int
+	Float   float64
 	Boolean bool
 	String  string
 	Array   struct {
 		Elems []Value
 	}
+	Tuple struct {
+		Elems []Value
+	}
+	Map struct {
+		Keys []Value // Keys in insertion order, so that printing and iteration are deterministic.
+		Vals map[Value]Value
+	}
+	Range struct {
+		Lo        Integer
+		Hi        Integer
+		Inclusive bool
+	}
 	Fn struct {
 		Params []*bir.VarDecl
 		Body   bir.Expr
+		Env    *frame // The frame of the enclosing function, if any variables are captured.
+	}
+	Method struct {
+		Recv *Instance
+		Fn   *Fn
 	}
 	Instance struct {
 		Ident  *ir.Ident
 		Fields map[string]Value
+		Names  []string // Field names in declaration order.
+	}
+	Variant struct {
+		Enum   *ir.Ident
+		Name   string
+		Fields []Value // The payload, if any.
 	}
 	RetVal struct {
 		V Value
 	}
 	BreakVal struct {
-		V Value
+		V    Value
+		Loop *bir.ForExpr
+	}
+	ContinueVal struct {
+		Loop *bir.ForExpr
 	}
 	Intrinsic ir.Intrinsic
+	Nil       struct{}
 	Unit      struct{}
 )
 
-func (Integer) sealed()   {}
-func (Boolean) sealed()   {}
-func (String) sealed()    {}
-func (*Array) sealed()    {}
-func (*Fn) sealed()       {}
-func (*Instance) sealed() {}
-func (*RetVal) sealed()   {}
-func (*BreakVal) sealed() {}
-func (Intrinsic) sealed() {}
-func (Unit) sealed()      {}
+func (Integer) sealed()      {}
+func (Float) sealed()        {}
+func (Boolean) sealed()      {}
+func (String) sealed()       {}
+func (*Array) sealed()       {}
+func (*Tuple) sealed()       {}
+func (*Map) sealed()         {}
+func (*Range) sealed()       {}
+func (*Fn) sealed()          {}
+func (*Method) sealed()      {}
+func (*Instance) sealed()    {}
+func (*Variant) sealed()     {}
+func (*RetVal) sealed()      {}
+func (*BreakVal) sealed()    {}
+func (*ContinueVal) sealed() {}
+func (Intrinsic) sealed()    {}
+func (Nil) sealed()          {}
+func (Unit) sealed()         {}
 
 func (i Integer) String() string {
 	return fmt.Sprintf("%d", i)
 }
 
+// String formats float f with the fewest digits needed to represent it,
+// but always with a fractional part or an exponent so that it does not
+// look like an integer.
+func (f Float) String() string {
+	s := strconv.FormatFloat(float64(f), 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+	return s + ".0"
+}
+
 func (b Boolean) String() string {
 	return fmt.Sprintf("%t", b)
 }
@@ -76,25 +125,87 @@ func (a *Array) String() string {
 	return builder.String()
 }
 
+func (t *Tuple) String() string {
+	var builder strings.Builder
+
+	builder.WriteByte('(')
+	for i, elem := range t.Elems {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(elem.String())
+	}
+	if len(t.Elems) == 1 {
+		builder.WriteByte(',')
+	}
+	builder.WriteByte(')')
+
+	return builder.String()
+}
+
+func (m *Map) String() string {
+	var builder strings.Builder
+
+	builder.WriteByte('{')
+	for i, key := range m.Keys {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(key.String())
+		builder.WriteString(": ")
+		builder.WriteString(m.Vals[key].String())
+	}
+	builder.WriteByte('}')
+
+	return builder.String()
+}
+
+func (r *Range) String() string {
+	if r.Inclusive {
+		return fmt.Sprintf("%d..=%d", r.Lo, r.Hi)
+	}
+	return fmt.Sprintf("%d..%d", r.Lo, r.Hi)
+}
+
 func (f *Fn) String() string {
 	return "fn"
 }
 
+func (m *Method) String() string {
+	return "fn"
+}
+
 func (ins *Instance) String() string {
 	var builder strings.Builder
 	builder.WriteString(ins.Ident.Name)
 	builder.WriteByte('{')
 
-	i := 0
-	for _, f := range ins.Fields {
+	for i, name := range ins.Names {
 		if i > 0 {
 			builder.WriteString(", ")
 		}
+		builder.WriteString(ins.Fields[name].String())
+	}
+	builder.WriteByte('}')
+
+	return builder.String()
+}
+
+func (v *Variant) String() string {
+	if len(v.Fields) == 0 {
+		return v.Name
+	}
 
+	var builder strings.Builder
+	builder.WriteString(v.Name)
+	builder.WriteByte('(')
+	for i, f := range v.Fields {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
 		builder.WriteString(f.String())
-		i += 1
 	}
-	builder.WriteByte('}')
+	builder.WriteByte(')')
 
 	return builder.String()
 }
@@ -107,10 +218,18 @@ func (r *BreakVal) String() string {
 	return r.V.String()
 }
 
+func (c *ContinueVal) String() string {
+	return "()"
+}
+
 func (i Intrinsic) String() string {
 	return ir.Intrinsic(i).String()
 }
 
+func (n Nil) String() string {
+	return "nil"
+}
+
 func (u Unit) String() string {
 	return "()"
 }
@@ -118,3 +237,22 @@ func (u Unit) String() string {
 func (ins *Instance) Get(id *ir.Ident) Value {
 	return ins.Fields[id.Name]
 }
+
+func NewMap() *Map {
+	return &Map{Vals: make(map[Value]Value)}
+}
+
+// Get returns the value of key and a boolean true, if it is present.
+// Otherwise, returns nil and a boolean false.
+func (m *Map) Get(key Value) (Value, bool) {
+	v, ok := m.Vals[key]
+	return v, ok
+}
+
+// Set associates val with key, which keeps its position if it is already present.
+func (m *Map) Set(key, val Value) {
+	if _, ok := m.Vals[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Vals[key] = val
+}
@@ -52,6 +52,9 @@ func (p *parser) parseItem() ast.Item {
 	if classSp, ok := p.eat(token.Class); ok {
 		return p.parseClassDecl(classSp)
 	}
+	if enumSp, ok := p.eat(token.Enum); ok {
+		return p.parseEnumDecl(enumSp)
+	}
 	return nil
 }
 
@@ -63,6 +66,11 @@ func (p *parser) parseFnDecl(fnSp span.Span) ast.Item {
 		p.error("expected function name, but got `%s`", p.tok.Kind)
 	}
 
+	tyParams, ok := p.parseTyParams()
+	if !ok {
+		return &ast.ErrItem{}
+	}
+
 	params := p.parseParams()
 	if params == nil {
 		return nil
@@ -81,10 +89,102 @@ func (p *parser) parseFnDecl(fnSp span.Span) ast.Item {
 
 	body := p.parseBlockExpr()
 	sp := fnSp.To(body.Span())
-	return &ast.FnDecl{Ident: ident, In: params, Out: ty, Body: body, Sp: sp}
+	return &ast.FnDecl{Ident: ident, TyParams: tyParams, In: params, Out: ty, Body: body, Sp: sp}
+}
+
+// parseEnumDecl parses `enum ident { ident [(ty, ...)], ... }`.
+// `enum` token already eaten.
+func (p *parser) parseEnumDecl(enumSp span.Span) ast.Item {
+	ident := p.parseIdent()
+	if ident == nil {
+		p.error("expected enum name, but got `%s`", p.tok.Kind)
+		return &ast.ErrItem{}
+	}
+
+	if _, ok := p.eat(token.LBrace); !ok {
+		p.error("expected opening delimiter `%s`", token.LBrace)
+		return &ast.ErrItem{}
+	}
+
+	var variants []*ast.Variant
+	for !p.tok.IsOneOf(token.RBrace, token.Eof) {
+		variant := p.parseVariant()
+		if variant == nil {
+			return &ast.ErrItem{}
+		}
+		variants = append(variants, variant)
+
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RBrace)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RBrace)
+		return &ast.ErrItem{}
+	}
+
+	sp := enumSp.To(closeSp)
+	return &ast.EnumDecl{Ident: ident, Variants: variants, Sp: sp}
+}
+
+func (p *parser) parseVariant() *ast.Variant {
+	ident := p.parseIdent()
+	if ident == nil {
+		p.error("expected variant name, but got `%s`", p.tok.Kind)
+		return nil
+	}
+
+	if _, ok := p.eat(token.LParen); !ok {
+		return &ast.Variant{Ident: ident, Sp: ident.Sp}
+	}
+
+	var tys []*ast.Ty
+	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		ty := p.parseTy()
+		if ty == nil {
+			return nil
+		}
+		tys = append(tys, ty)
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RParen)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RParen)
+		return nil
+	}
+	return &ast.Variant{Ident: ident, Tys: tys, Sp: ident.Sp.To(closeSp)}
+}
+
+// parseFnExpr parses `fn(params) [: ty] { exprs }`.
+// `fn` token already eaten.
+func (p *parser) parseFnExpr(fnSp span.Span) ast.Expr {
+	params := p.parseParams()
+	if params == nil {
+		return &ast.ErrExpr{}
+	}
+
+	var ty *ast.Ty
+	if sp, ok := p.eat(token.Colon); ok {
+		ty = p.parseTy()
+	} else {
+		ty = &ast.Ty{Kind: ast.TyUnit, Sp: sp}
+	}
+
+	if ty == nil {
+		return &ast.ErrExpr{}
+	}
+
+	body := p.parseBlockExpr()
+	sp := fnSp.To(body.Span())
+	return &ast.FnExpr{In: params, Out: ty, Body: body, Sp: sp}
 }
 
-// parseClassDecl parses `class ident { fields }`.
+// parseClassDecl parses `class ident { fields methods }`.
 // `class` token already eaten.
 func (p *parser) parseClassDecl(classSp span.Span) ast.Item {
 	ident := p.parseIdent()
@@ -93,13 +193,26 @@ func (p *parser) parseClassDecl(classSp span.Span) ast.Item {
 		return &ast.ErrItem{}
 	}
 
+	tyParams, ok := p.parseTyParams()
+	if !ok {
+		return &ast.ErrItem{}
+	}
+
 	if _, ok := p.eat(token.LBrace); !ok {
 		p.error("expected opening delimiter `%s`", token.LBrace)
 		return &ast.ErrItem{}
 	}
 
 	var fields []*ast.VarDecl
+	var methods []*ast.FnDecl
 	for !p.tok.IsOneOf(token.RBrace, token.Eof) {
+		if fnSp, ok := p.eat(token.Fn); ok {
+			if method, ok := p.parseFnDecl(fnSp).(*ast.FnDecl); ok {
+				methods = append(methods, method)
+			}
+			continue
+		}
+
 		ident := p.parseIdent()
 		if ident == nil {
 			p.error("expected field name, but got `%s`", p.tok.Kind)
@@ -118,7 +231,7 @@ func (p *parser) parseClassDecl(classSp span.Span) ast.Item {
 		field := &ast.VarDecl{Ident: ident, Ty: ty}
 		fields = append(fields, field)
 
-		if _, ok := p.eat(token.Comma); !ok {
+		if _, ok := p.eat(token.Comma); !ok && !p.tok.Is(token.Fn) {
 			break
 		}
 	}
@@ -130,7 +243,7 @@ func (p *parser) parseClassDecl(classSp span.Span) ast.Item {
 	}
 
 	sp := classSp.To(closeSp)
-	return &ast.ClassDecl{Ident: ident, Fields: fields, Sp: sp}
+	return &ast.ClassDecl{Ident: ident, TyParams: tyParams, Fields: fields, Methods: methods, Sp: sp}
 }
 
 func (p *parser) parseParams() []*ast.VarDecl {
@@ -142,6 +255,17 @@ func (p *parser) parseParams() []*ast.VarDecl {
 	}
 
 	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		if sp, ok := p.eat(token.Self); ok {
+			ident := &ast.Ident{Name: p.prevTok.Lit, Sp: sp}
+			param := &ast.VarDecl{Ident: ident, Ty: &ast.Ty{Kind: ast.TySelf, Sp: sp}}
+			params = append(params, param)
+
+			if _, ok := p.eat(token.Comma); !ok {
+				break
+			}
+			continue
+		}
+
 		ident := p.parseIdent()
 		if ident == nil {
 			p.error("expected parameter name, but got `%s`", p.tok.Kind)
@@ -186,14 +310,23 @@ func (p *parser) parseExpr() ast.Expr {
 		return p.parseBreakExpr(sp)
 	}
 
+	if sp, ok := p.eat(token.Continue); ok {
+		return p.parseContinueExpr(sp)
+	}
+
 	return p.parsePrecExpr(0)
 }
 
 // parseLetExpr parses a let binding, `let` token already eaten.
-// `let ident [: ty] = init`
+// `let ident [: ty] = init` or `let (pats) [: ty] = init`
 func (p *parser) parseLetExpr(let_sp span.Span) ast.Expr {
-	ident := p.parseIdent()
-	if ident == nil {
+	var pat ast.Pat
+	var ident *ast.Ident
+	if p.tok.Is(token.LParen) {
+		if pat = p.parsePat(); pat == nil {
+			return &ast.ErrExpr{}
+		}
+	} else if ident = p.parseIdent(); ident == nil {
 		p.error("expected identifier in let binding, but got `%s`", p.tok.Kind)
 	}
 
@@ -213,18 +346,18 @@ func (p *parser) parseLetExpr(let_sp span.Span) ast.Expr {
 		p.error("expected expression, but got `%s`", p.tok.Kind)
 	}
 
-	if ident == nil || init == nil || ty == nil {
+	if (ident == nil && pat == nil) || init == nil || ty == nil {
 		return &ast.ErrExpr{}
 	}
 
 	sp := let_sp.To(init.Span())
-	return &ast.LetExpr{Decl: &ast.VarDecl{Ident: ident, Ty: ty}, Init: init, Sp: sp}
+	return &ast.LetExpr{Decl: &ast.VarDecl{Ident: ident, Ty: ty}, Pat: pat, Init: init, Sp: sp}
 }
 
 func (p *parser) parseReturnExpr(retSp span.Span) ast.Expr {
 	retLine := p.sess.File.Line(retSp.Start)
 	currLine := p.sess.File.Line(p.tok.Sp.Start)
-	if retLine == currLine {
+	if retLine == currLine && !p.tok.IsOneOf(token.RBrace, token.Eof) {
 		if expr := p.parseExpr(); expr != nil {
 			return &ast.ReturnExpr{X: expr, Sp: retSp.To(expr.Span())}
 		}
@@ -233,28 +366,67 @@ func (p *parser) parseReturnExpr(retSp span.Span) ast.Expr {
 	return &ast.ReturnExpr{Sp: retSp}
 }
 
-// parseBreakExpr parses `break [expr]`
+// parseBreakExpr parses `break ['label] [expr]`
 // `break` token already eaten.
 func (p *parser) parseBreakExpr(breakSp span.Span) ast.Expr {
+	sp := breakSp
+	label := p.parseLabel(breakSp)
+	if label != nil {
+		sp = breakSp.To(label.Sp)
+	}
+
 	retLine := p.sess.File.Line(breakSp.Start)
 	currLine := p.sess.File.Line(p.tok.Sp.Start)
-	if retLine == currLine {
+	if retLine == currLine && !p.tok.IsOneOf(token.RBrace, token.Eof) {
 		if expr := p.parseExpr(); expr != nil {
-			return &ast.BreakExpr{X: expr, Sp: breakSp.To(expr.Span())}
+			return &ast.BreakExpr{Label: label, X: expr, Sp: breakSp.To(expr.Span())}
 		}
 	}
 
-	return &ast.BreakExpr{Sp: breakSp}
+	return &ast.BreakExpr{Label: label, Sp: sp}
+}
+
+// parseContinueExpr parses `continue ['label]`
+// `continue` token already eaten.
+func (p *parser) parseContinueExpr(continueSp span.Span) ast.Expr {
+	sp := continueSp
+	label := p.parseLabel(continueSp)
+	if label != nil {
+		sp = continueSp.To(label.Sp)
+	}
+
+	return &ast.ContinueExpr{Label: label, Sp: sp}
+}
+
+// parseLabel parses the optional label of a `break` or `continue`,
+// which has to be on the same line as the keyword at kwSp.
+func (p *parser) parseLabel(kwSp span.Span) *ast.Ident {
+	kwLine := p.sess.File.Line(kwSp.Start)
+	currLine := p.sess.File.Line(p.tok.Sp.Start)
+	if kwLine != currLine {
+		return nil
+	}
+
+	if sp, ok := p.eat(token.Label); ok {
+		return &ast.Ident{Name: p.prevTok.Lit, Sp: sp}
+	}
+	return nil
 }
 
 func (p *parser) parsePrecExpr(min_prec int) ast.Expr {
-	expr := p.parseCallOrIndexExpr()
+	expr := p.parseUnaryExpr()
 
 	for {
 		op, ok := ast.BinOpFromToken(p.tok)
 		if !ok || op.Prec() < min_prec {
 			break
 		}
+
+		// A `-` at the start of a line begins a new expression, e.g., `-1`,
+		// rather than continuing the previous one.
+		if op.Kind == ast.Sub && p.onNewLine() {
+			break
+		}
 		p.next()
 
 		prec := op.Prec()
@@ -267,6 +439,12 @@ func (p *parser) parsePrecExpr(min_prec int) ast.Expr {
 		switch op.Kind {
 		case ast.Assign:
 			expr = &ast.AssignExpr{X: expr, Y: rhs, Sp: sp}
+		case ast.AddAssign, ast.SubAssign, ast.MulAssign, ast.DivAssign:
+			op, _ := op.CompoundOp()
+			expr = &ast.CompoundAssignExpr{X: expr, Op: op, Y: rhs, Sp: sp}
+		case ast.Range, ast.RangeInclusive:
+			inclusive := op.Kind == ast.RangeInclusive
+			expr = &ast.RangeExpr{Lo: expr, Hi: rhs, Inclusive: inclusive, Sp: sp}
 		default:
 			expr = &ast.BinaryExpr{X: expr, Op: op, Y: rhs, Sp: sp}
 		}
@@ -275,57 +453,97 @@ func (p *parser) parsePrecExpr(min_prec int) ast.Expr {
 	return expr
 }
 
-func (p *parser) parseCallOrIndexExpr() ast.Expr {
-	expr := p.parseFieldExpr()
+// parseUnaryExpr parses `op expr`, where op is a unary operator.
+// Unary operators bind tighter than binary operators, but looser than calls,
+// indexing and field accesses, e.g., `-x.y` is parsed as `-(x.y)`.
+func (p *parser) parseUnaryExpr() ast.Expr {
+	if op, ok := ast.UnOpFromToken(p.tok); ok {
+		p.next()
+		x := p.parseUnaryExpr()
+		sp := op.Sp.To(x.Span())
+		return &ast.UnaryExpr{Op: op, X: x, Sp: sp}
+	}
 
-	if _, ok := p.eat(token.LParen); ok {
-		var args []ast.Expr
-		for !p.tok.IsOneOf(token.RParen, token.Eof) {
-			args = append(args, p.parseExpr())
-			if _, ok := p.eat(token.Comma); !ok {
-				break
-			}
+	return p.parsePostfixExpr()
+}
+
+// parsePostfixExpr parses an expression followed by any number of calls,
+// indexing and field accesses, e.g., `a.b(c)[0].d`.
+func (p *parser) parsePostfixExpr() ast.Expr {
+	expr := p.parseBotExpr()
+
+	for {
+		// A `(` or `[` at the start of a line begins a new expression,
+		// e.g., an array literal, rather than calling or indexing the previous one.
+		if p.tok.IsOneOf(token.LParen, token.LBrack) && p.onNewLine() {
+			return expr
 		}
 
-		rpSp, ok := p.eat(token.RParen)
-		if !ok {
-			p.error("expected closing delimiter `%s`", token.RParen)
-			return &ast.ErrExpr{}
+		switch p.tok.Kind {
+		case token.LParen:
+			expr = p.parseCallExpr(expr)
+		case token.LBrack:
+			expr = p.parseIndexExpr(expr)
+		case token.Dot:
+			expr = p.parseFieldExpr(expr)
+		default:
+			return expr
 		}
 
-		sp := expr.Span().To(rpSp)
-		return &ast.CallExpr{Fn: expr, Args: args, Sp: sp}
+		if _, ok := expr.(*ast.ErrExpr); ok {
+			return expr
+		}
 	}
+}
 
-	if _, ok := p.eat(token.LBrack); ok {
-		i := p.parseExpr()
-
-		closeSp, ok := p.eat(token.RBrack)
-		if !ok {
-			p.error("expected closing delimiter `%s`", token.RBrack)
-			return &ast.ErrExpr{}
+func (p *parser) parseCallExpr(fn ast.Expr) ast.Expr {
+	p.next()
+	var args []ast.Expr
+	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		args = append(args, p.parseExpr())
+		if _, ok := p.eat(token.Comma); !ok {
+			break
 		}
-		sp := expr.Span().To(closeSp)
-		return &ast.IndexExpr{Arr: expr, I: i, Sp: sp}
 	}
 
-	return expr
+	rpSp, ok := p.eat(token.RParen)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RParen)
+		return &ast.ErrExpr{}
+	}
+
+	sp := fn.Span().To(rpSp)
+	return &ast.CallExpr{Fn: fn, Args: args, Sp: sp}
 }
 
-func (p *parser) parseFieldExpr() ast.Expr {
-	expr := p.parseBotExpr()
+func (p *parser) parseIndexExpr(arr ast.Expr) ast.Expr {
+	p.next()
+	i := p.parseExpr()
 
-	if _, ok := p.eat(token.Dot); ok {
-		ident := p.parseIdent()
-		if ident == nil {
-			p.error("expected identifier after `.`")
-			return &ast.ErrExpr{}
-		}
-		sp := expr.Span().To(ident.Sp)
-		return &ast.FieldExpr{Expr: expr, Ident: ident, Sp: sp}
+	closeSp, ok := p.eat(token.RBrack)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RBrack)
+		return &ast.ErrExpr{}
 	}
+	sp := arr.Span().To(closeSp)
+	return &ast.IndexExpr{Arr: arr, I: i, Sp: sp}
+}
 
-	return expr
+func (p *parser) parseFieldExpr(expr ast.Expr) ast.Expr {
+	p.next()
+	var ident *ast.Ident
+	if sp, ok := p.eat(token.Number); ok {
+		// A tuple index, e.g., `t.0`.
+		ident = &ast.Ident{Name: p.prevTok.Lit, Sp: sp}
+	} else {
+		ident = p.parseIdent()
+	}
+	if ident == nil {
+		p.error("expected identifier or tuple index after `.`")
+		return &ast.ErrExpr{}
+	}
+	sp := expr.Span().To(ident.Sp)
+	return &ast.FieldExpr{Expr: expr, Ident: ident, Sp: sp}
 }
 
 func (p *parser) parseBotExpr() ast.Expr {
@@ -334,17 +552,37 @@ func (p *parser) parseBotExpr() ast.Expr {
 	}
 
 	if sp, ok := p.eat(token.For); ok {
-		return p.parseForExpr(sp)
+		return p.parseForExpr(sp, nil)
+	}
+
+	if sp, ok := p.eat(token.Match); ok {
+		return p.parseMatchExpr(sp)
+	}
+
+	if sp, ok := p.eat(token.Label); ok {
+		return p.parseLabeledExpr(sp)
+	}
+
+	if sp, ok := p.eat(token.Fn); ok {
+		return p.parseFnExpr(sp)
 	}
 
 	if sp, ok := p.eat(token.Number); ok {
 		return &ast.IntegerLiteral{V: p.prevTok.Lit, Sp: sp}
 	}
 
+	if sp, ok := p.eat(token.Float); ok {
+		return &ast.FloatLiteral{V: p.prevTok.Lit, Sp: sp}
+	}
+
 	if sp, ok := p.eat(token.String); ok {
 		return &ast.StringLiteral{V: p.prevTok.Lit, Sp: sp}
 	}
 
+	if sp, ok := p.eat(token.StringHead); ok {
+		return p.parseInterpExpr(sp)
+	}
+
 	if sp, ok := p.eat(token.False); ok {
 		return &ast.BooleanLiteral{V: false, Sp: sp}
 	}
@@ -353,10 +591,26 @@ func (p *parser) parseBotExpr() ast.Expr {
 		return &ast.BooleanLiteral{V: true, Sp: sp}
 	}
 
+	if sp, ok := p.eat(token.Nil); ok {
+		return &ast.NilLiteral{Sp: sp}
+	}
+
 	if sp, ok := p.eat(token.LBrack); ok {
 		return p.parseArrayExpr(sp)
 	}
 
+	if sp, ok := p.eat(token.LParen); ok {
+		return p.parseParenExpr(sp)
+	}
+
+	if sp, ok := p.eat(token.LBrace); ok {
+		return p.parseMapExpr(sp)
+	}
+
+	if sp, ok := p.eat(token.Self); ok {
+		return &ast.Ident{Name: p.prevTok.Lit, Sp: sp}
+	}
+
 	ident := p.parseIdent()
 	if ident != nil {
 		return p.parseClassExpr(ident)
@@ -365,8 +619,11 @@ func (p *parser) parseBotExpr() ast.Expr {
 	return ident
 }
 
+// parseClassExpr parses `ident { fields }`.
+// It requires the `{` to be followed by `ident:`, so that a condition followed by
+// a block, e.g., `for running { stop = true }`, is not parsed as a class literal.
 func (p *parser) parseClassExpr(ident *ast.Ident) ast.Expr {
-	if !p.lookahead(0).Is(token.Ident) {
+	if !p.lookahead(0).Is(token.Ident) || !p.lookahead(1).Is(token.Colon) {
 		return ident
 	}
 
@@ -432,6 +689,116 @@ func (p *parser) parseArrayExpr(openSp span.Span) ast.Expr {
 	return &ast.ArrayExpr{Exprs: exprs, Sp: sp}
 }
 
+// parseInterpExpr parses `"str{expr}str..."`
+// The head of the string, up to and including the first `{`, already eaten.
+func (p *parser) parseInterpExpr(headSp span.Span) ast.Expr {
+	var parts []ast.Expr
+	if lit := p.prevTok.Lit; lit != "" {
+		parts = append(parts, &ast.StringLiteral{V: lit, Sp: headSp})
+	}
+
+	for {
+		if p.tok.IsOneOf(token.StringMid, token.StringTail) {
+			p.error("expected expression in string interpolation, but got `}`")
+			p.skipInterp()
+			return &ast.ErrExpr{}
+		}
+		parts = append(parts, p.parseExpr())
+
+		sp, ok := p.eat(token.StringMid)
+		if !ok {
+			sp, ok = p.eat(token.StringTail)
+		}
+		if !ok {
+			p.error("expected `}` after interpolated expression, but got `%s`", p.tok.Kind)
+			p.skipInterp()
+			return &ast.ErrExpr{}
+		}
+		if lit := p.prevTok.Lit; lit != "" {
+			parts = append(parts, &ast.StringLiteral{V: lit, Sp: sp})
+		}
+		if p.prevTok.Is(token.StringTail) {
+			return &ast.InterpExpr{Parts: parts, Sp: headSp.To(sp)}
+		}
+	}
+}
+
+// skipInterp skips the rest of the interpolated string being parsed,
+// including any nested ones, so that parsing can resume after it.
+func (p *parser) skipInterp() {
+	depth := 0
+	for !p.tok.Is(token.Eof) {
+		switch p.tok.Kind {
+		case token.StringHead:
+			depth++
+		case token.StringTail:
+			if depth == 0 {
+				p.next()
+				return
+			}
+			depth--
+		}
+		p.next()
+	}
+}
+
+// parseMapExpr parses `{key: val, ...}`
+// `{` token already eaten.
+func (p *parser) parseMapExpr(openSp span.Span) ast.Expr {
+	var entries []*ast.MapEntry
+	for !p.tok.IsOneOf(token.RBrace, token.Eof) {
+		key := p.parseExpr()
+		if _, ok := p.eat(token.Colon); !ok {
+			p.error("expected `%s` after map key, but got `%s`", token.Colon, p.tok.Kind)
+			return &ast.ErrExpr{}
+		}
+		val := p.parseExpr()
+		entries = append(entries, &ast.MapEntry{Key: key, Val: val})
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RBrace)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RBrace)
+		return &ast.ErrExpr{}
+	}
+
+	sp := openSp.To(closeSp)
+	return &ast.MapExpr{Entries: entries, Sp: sp}
+}
+
+// parseParenExpr parses `(expr)` or a tuple `(expr, expr...)`.
+// A tuple with a single element needs a trailing comma, e.g., `(1,)`.
+// `(` token already eaten.
+func (p *parser) parseParenExpr(openSp span.Span) ast.Expr {
+	if p.tok.Is(token.RParen) {
+		p.error("expected expression, but got `%s`", p.tok.Kind)
+		return &ast.ErrExpr{}
+	}
+
+	var exprs []ast.Expr
+	trailing := false
+	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		exprs = append(exprs, p.parseExpr())
+		if _, trailing = p.eat(token.Comma); !trailing {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RParen)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RParen)
+		return &ast.ErrExpr{}
+	}
+
+	if len(exprs) == 1 && !trailing {
+		return exprs[0]
+	}
+	return &ast.TupleExpr{Exprs: exprs, Sp: openSp.To(closeSp)}
+}
+
 // parseIfExpr parses `if cond { exprs } [else [if cond] { exprs ]`
 // `if` token already eaten.
 func (p *parser) parseIfExpr(ifSp span.Span) ast.Expr {
@@ -459,12 +826,277 @@ func (p *parser) parseElseExpr() ast.Expr {
 	return p.parseBlockExpr()
 }
 
-// parseForExpr parses `for { exprs }`
-// `for` token already eaten.
-func (p *parser) parseForExpr(forSp span.Span) ast.Expr {
+// parseMatchExpr parses `match x { pat [if guard] => body, ... }`.
+// `match` token already eaten. The `,` after an arm may be left out
+// if the next arm starts on a new line.
+func (p *parser) parseMatchExpr(matchSp span.Span) ast.Expr {
+	x := p.parseExpr()
+
+	if _, ok := p.eat(token.LBrace); !ok {
+		p.error("expected opening delimiter `%s`", token.LBrace)
+		return &ast.ErrExpr{}
+	}
+
+	var arms []*ast.MatchArm
+	for !p.tok.IsOneOf(token.RBrace, token.Eof) {
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return &ast.ErrExpr{}
+		}
+		arms = append(arms, arm)
+
+		if _, ok := p.eat(token.Comma); !ok && !p.tok.Is(token.RBrace) && !p.onNewLine() {
+			p.error("expected `,` after match arm, but got `%s`", p.tok.Kind)
+			return &ast.ErrExpr{}
+		}
+	}
+
+	closeSp, ok := p.eat(token.RBrace)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RBrace)
+		return &ast.ErrExpr{}
+	}
+
+	sp := matchSp.To(closeSp)
+	return &ast.MatchExpr{X: x, Arms: arms, Sp: sp}
+}
+
+// parseMatchArm parses `pat [if guard] => body`.
+func (p *parser) parseMatchArm() *ast.MatchArm {
+	pat := p.parsePat()
+	if pat == nil {
+		return nil
+	}
+
+	var guard ast.Expr
+	if _, ok := p.eat(token.If); ok {
+		guard = p.parseExpr()
+	}
+
+	if _, ok := p.eat(token.FatArrow); !ok {
+		p.error("expected `%s`, but got `%s`", token.FatArrow, p.tok.Kind)
+		return nil
+	}
+
+	var body ast.Expr
+	if p.tok.Is(token.LBrace) {
+		body = p.parseBlockExpr()
+	} else {
+		body = p.parseExpr()
+	}
+	sp := pat.Span().To(body.Span())
+	return &ast.MatchArm{Pat: pat, Guard: guard, Body: body, Sp: sp}
+}
+
+// parsePat parses a pattern, or reports an error and returns nil if there is none.
+func (p *parser) parsePat() ast.Pat {
+	if p.tok.IsOneOf(token.Number, token.Minus) {
+		lo := p.parseIntPat()
+		if lo == nil {
+			return nil
+		}
+		if !p.tok.IsOneOf(token.DotDot, token.DotDotEq) {
+			return &ast.LitPat{X: lo, Sp: lo.Sp}
+		}
+
+		inclusive := p.tok.Is(token.DotDotEq)
+		p.next()
+		hi := p.parseIntPat()
+		if hi == nil {
+			return nil
+		}
+		sp := lo.Sp.To(hi.Sp)
+		return &ast.RangePat{Lo: lo, Hi: hi, Inclusive: inclusive, Sp: sp}
+	}
+
+	if sp, ok := p.eat(token.String); ok {
+		return &ast.LitPat{X: &ast.StringLiteral{V: p.prevTok.Lit, Sp: sp}, Sp: sp}
+	}
+
+	if openSp, ok := p.eat(token.LParen); ok {
+		return p.parseTuplePat(openSp)
+	}
+
+	if sp, ok := p.eat(token.False); ok {
+		return &ast.LitPat{X: &ast.BooleanLiteral{V: false, Sp: sp}, Sp: sp}
+	}
+
+	if sp, ok := p.eat(token.True); ok {
+		return &ast.LitPat{X: &ast.BooleanLiteral{V: true, Sp: sp}, Sp: sp}
+	}
+
+	ident := p.parseIdent()
+	if ident == nil {
+		p.error("expected pattern, but got `%s`", p.tok.Kind)
+		return nil
+	}
+
+	switch {
+	case ident.Name == "_":
+		return &ast.WildPat{Sp: ident.Sp}
+	case p.tok.Is(token.Dot):
+		return p.parseVariantPat(ident)
+	case p.tok.Is(token.LBrace):
+		return p.parseClassPat(ident)
+	default:
+		return &ast.BindPat{Ident: ident}
+	}
+}
+
+// parseIntPat parses an integer literal in a pattern, e.g., `1` or `-1`.
+func (p *parser) parseIntPat() *ast.IntegerLiteral {
+	minusSp, neg := p.eat(token.Minus)
+	sp, ok := p.eat(token.Number)
+	if !ok {
+		p.error("expected integer, but got `%s`", p.tok.Kind)
+		return nil
+	}
+
+	if neg {
+		return &ast.IntegerLiteral{V: "-" + p.prevTok.Lit, Sp: minusSp.To(sp)}
+	}
+	return &ast.IntegerLiteral{V: p.prevTok.Lit, Sp: sp}
+}
+
+// parseTuplePat parses `(pats)`, or a parenthesized pattern `(pat)`.
+// `(` token already eaten.
+func (p *parser) parseTuplePat(openSp span.Span) ast.Pat {
+	var pats []ast.Pat
+	trailing := false
+	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		pat := p.parsePat()
+		if pat == nil {
+			return nil
+		}
+		pats = append(pats, pat)
+		if _, trailing = p.eat(token.Comma); !trailing {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RParen)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RParen)
+		return nil
+	}
+
+	if len(pats) == 1 && !trailing {
+		return pats[0]
+	}
+	return &ast.TuplePat{Pats: pats, Sp: openSp.To(closeSp)}
+}
+
+// parseVariantPat parses `enum.variant[(pats)]`.
+// The enum name is already parsed.
+func (p *parser) parseVariantPat(enum *ast.Ident) ast.Pat {
+	p.next()
+	variant := p.parseIdent()
+	if variant == nil {
+		p.error("expected identifier after `.`")
+		return nil
+	}
+
+	if _, ok := p.eat(token.LParen); !ok {
+		return &ast.VariantPat{Enum: enum, Variant: variant, Sp: enum.Sp.To(variant.Sp)}
+	}
+
+	pats := make([]ast.Pat, 0)
+	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		pat := p.parsePat()
+		if pat == nil {
+			return nil
+		}
+		pats = append(pats, pat)
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RParen)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RParen)
+		return nil
+	}
+	return &ast.VariantPat{Enum: enum, Variant: variant, Pats: pats, Sp: enum.Sp.To(closeSp)}
+}
+
+// parseClassPat parses `ident { ident [: pat], ... }`.
+// The class name is already parsed.
+func (p *parser) parseClassPat(ident *ast.Ident) ast.Pat {
+	p.next()
+	var fields []*ast.PatField
+	for !p.tok.IsOneOf(token.RBrace, token.Eof) {
+		name := p.parseIdent()
+		if name == nil {
+			p.error("expected field name, but got `%s`", p.tok.Kind)
+			return nil
+		}
+
+		var pat ast.Pat = &ast.BindPat{Ident: name}
+		if _, ok := p.eat(token.Colon); ok {
+			if pat = p.parsePat(); pat == nil {
+				return nil
+			}
+		}
+		fields = append(fields, &ast.PatField{Ident: name, Pat: pat})
+
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RBrace)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RBrace)
+		return nil
+	}
+	return &ast.ClassPat{Ident: ident, Fields: fields, Sp: ident.Sp.To(closeSp)}
+}
+
+// parseLabeledExpr parses `'label: for ...`
+// label token already eaten.
+func (p *parser) parseLabeledExpr(labelSp span.Span) ast.Expr {
+	label := &ast.Ident{Name: p.prevTok.Lit, Sp: labelSp}
+	if _, ok := p.eat(token.Colon); !ok {
+		p.error("expected `:` after label `%s`", label.Name)
+		return &ast.ErrExpr{}
+	}
+
+	forSp, ok := p.eat(token.For)
+	if !ok {
+		p.error("expected `for` after label `%s`, but got `%s`", label.Name, p.tok.Kind)
+		return &ast.ErrExpr{}
+	}
+
+	return p.parseForExpr(forSp, label)
+}
+
+// parseForExpr parses `for [cond | ident [, ident] in iter] { exprs }`
+// `for` token already eaten, and so is the label, if any.
+func (p *parser) parseForExpr(forSp span.Span, label *ast.Ident) ast.Expr {
+	var cond, iter ast.Expr
+	var ident, val *ast.Ident
+	if p.tok.Is(token.Ident) && p.lookahead(0).Is(token.In) {
+		ident = p.parseIdent()
+		p.next()
+		iter = p.parseExpr()
+	} else if p.tok.Is(token.Ident) && p.lookahead(0).Is(token.Comma) &&
+		p.lookahead(1).Is(token.Ident) && p.lookahead(2).Is(token.In) {
+		ident = p.parseIdent()
+		p.next()
+		val = p.parseIdent()
+		p.next()
+		iter = p.parseExpr()
+	} else if !p.tok.Is(token.LBrace) {
+		cond = p.parseExpr()
+	}
+
 	body := p.parseBlockExpr()
 	sp := forSp.To(body.Span())
-	return &ast.ForExpr{Body: body, Sp: sp}
+	if label != nil {
+		sp = label.Sp.To(body.Span())
+	}
+	return &ast.ForExpr{Label: label, Cond: cond, Ident: ident, Val: val, Iter: iter, Body: body, Sp: sp}
 }
 
 // parseBlockExpr parses `{ exprs }`
@@ -490,29 +1122,200 @@ func (p *parser) parseBlockExpr() ast.Expr {
 	return &ast.BlockExpr{Exprs: exprs, Sp: sp}
 }
 
+// parseTy parses a type, followed by any number of `?` that make it optional.
 // `:` token already eaten.
 func (p *parser) parseTy() *ast.Ty {
+	ty := p.parseBaseTy()
+	if ty == nil {
+		return nil
+	}
+
+	for {
+		sp, ok := p.eat(token.Question)
+		if !ok {
+			return ty
+		}
+		ty = &ast.Ty{Kind: ast.TyOptional, Elem: ty, Sp: ty.Sp.To(sp)}
+	}
+}
+
+func (p *parser) parseBaseTy() *ast.Ty {
+	if openSp, ok := p.eat(token.LParen); ok {
+		return p.parseTupleTy(openSp)
+	}
+
 	if openSp, ok := p.eat(token.LBrack); ok {
-		ident := p.parseIdent()
-		if ident == nil {
-			p.error("expected a type after `[`")
+		elem := p.parseTy()
+		if elem == nil {
 			return nil
 		}
 		closeSp, ok := p.eat(token.RBrack)
 		if !ok {
-			p.error("expected closing delimiter `%s`", token.LBrack)
+			p.error("expected closing delimiter `%s`", token.RBrack)
 			return nil
 		}
 		sp := openSp.To(closeSp)
-		return &ast.Ty{Kind: ast.TyArray, Ident: ident, Sp: sp}
+		return &ast.Ty{Kind: ast.TyArray, Elem: elem, Sp: sp}
+	}
+
+	if openSp, ok := p.eat(token.LBrace); ok {
+		return p.parseMapTy(openSp)
+	}
+
+	if fnSp, ok := p.eat(token.Fn); ok {
+		return p.parseFnTy(fnSp)
 	}
 
 	ident := p.parseIdent()
 	if ident == nil {
-		p.error("expected type after `:`")
+		p.error("expected type after `%s`", p.prevTok.Kind)
+		return nil
+	}
+
+	if _, ok := p.eat(token.Lt); !ok {
+		return &ast.Ty{Kind: ast.TyIdent, Ident: ident, Sp: ident.Sp}
+	}
+
+	var args []*ast.Ty
+	for !p.tok.IsOneOf(token.Gt, token.Shr, token.Eof) {
+		arg := p.parseTy()
+		if arg == nil {
+			return nil
+		}
+		args = append(args, arg)
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	closeSp, ok := p.eatGt()
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.Gt)
+		return nil
+	}
+	sp := ident.Sp.To(closeSp)
+	return &ast.Ty{Kind: ast.TyIdent, Ident: ident, Args: args, Sp: sp}
+}
+
+// parseTupleTy parses a tuple type `(ty, ty...)`, the unit type `()`,
+// or a parenthesized type `(ty)`, e.g., `(fn(): int)?`.
+// `(` token already eaten.
+func (p *parser) parseTupleTy(openSp span.Span) *ast.Ty {
+	var elems []*ast.Ty
+	trailing := false
+	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		elem := p.parseTy()
+		if elem == nil {
+			return nil
+		}
+		elems = append(elems, elem)
+		if _, trailing = p.eat(token.Comma); !trailing {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RParen)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RParen)
 		return nil
 	}
-	return &ast.Ty{Kind: ast.TyIdent, Ident: ident, Sp: ident.Sp}
+
+	sp := openSp.To(closeSp)
+	switch {
+	case len(elems) == 0:
+		return &ast.Ty{Kind: ast.TyUnit, Sp: sp}
+	case len(elems) == 1 && !trailing:
+		return elems[0]
+	default:
+		return &ast.Ty{Kind: ast.TyTuple, Elems: elems, Sp: sp}
+	}
+}
+
+// parseMapTy parses `{ty: ty}`
+// `{` token already eaten.
+func (p *parser) parseMapTy(openSp span.Span) *ast.Ty {
+	key := p.parseTy()
+	if key == nil {
+		return nil
+	}
+	if _, ok := p.eat(token.Colon); !ok {
+		p.error("expected `%s` after map key type, but got `%s`", token.Colon, p.tok.Kind)
+		return nil
+	}
+	val := p.parseTy()
+	if val == nil {
+		return nil
+	}
+
+	closeSp, ok := p.eat(token.RBrace)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RBrace)
+		return nil
+	}
+	sp := openSp.To(closeSp)
+	return &ast.Ty{Kind: ast.TyMap, Key: key, Elem: val, Sp: sp}
+}
+
+// parseTyParams parses `<ident, ...>`, if any.
+func (p *parser) parseTyParams() ([]*ast.Ident, bool) {
+	if _, ok := p.eat(token.Lt); !ok {
+		return nil, true
+	}
+
+	var params []*ast.Ident
+	for !p.tok.IsOneOf(token.Gt, token.Eof) {
+		ident := p.parseIdent()
+		if ident == nil {
+			p.error("expected type parameter name, but got `%s`", p.tok.Kind)
+			return nil, false
+		}
+		params = append(params, ident)
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	if _, ok := p.eat(token.Gt); !ok {
+		p.error("expected closing delimiter `%s`", token.Gt)
+		return nil, false
+	}
+	return params, true
+}
+
+// parseFnTy parses `fn(ty, ...) [: ty]`.
+func (p *parser) parseFnTy(fnSp span.Span) *ast.Ty {
+	if _, ok := p.eat(token.LParen); !ok {
+		p.error("expected `%s` after `fn`", token.LParen)
+		return nil
+	}
+
+	var params []*ast.Ty
+	for !p.tok.IsOneOf(token.RParen, token.Eof) {
+		param := p.parseTy()
+		if param == nil {
+			return nil
+		}
+		params = append(params, param)
+		if _, ok := p.eat(token.Comma); !ok {
+			break
+		}
+	}
+
+	closeSp, ok := p.eat(token.RParen)
+	if !ok {
+		p.error("expected closing delimiter `%s`", token.RParen)
+		return nil
+	}
+
+	out := &ast.Ty{Kind: ast.TyUnit, Sp: closeSp}
+	if _, ok := p.eat(token.Colon); ok {
+		if out = p.parseTy(); out == nil {
+			return nil
+		}
+	}
+
+	sp := fnSp.To(out.Sp)
+	return &ast.Ty{Kind: ast.TyFn, Params: params, Out: out, Sp: sp}
 }
 
 func (p *parser) parseIdent() *ast.Ident {
@@ -522,6 +1325,14 @@ func (p *parser) parseIdent() *ast.Ident {
 	return nil
 }
 
+// onNewLine returns true if the current token is on a later line than the previous one,
+// otherwise false.
+func (p *parser) onNewLine() bool {
+	prevLine := p.sess.File.Line(p.prevTok.Sp.End)
+	currLine := p.sess.File.Line(p.tok.Sp.Start)
+	return currLine > prevLine
+}
+
 func (p *parser) lookahead(n int) token.Token {
 	i := p.pos + n
 	if i < len(p.tokens) {
@@ -541,6 +1352,18 @@ func (p *parser) eat(k token.Kind) (span.Span, bool) {
 	return sp, false
 }
 
+// eatGt is like eat(token.Gt), but also splits a `>>` token into two `>`
+// and eats the first one, so that nested type arguments like `Box<Box<int>>` can be closed.
+func (p *parser) eatGt() (span.Span, bool) {
+	if p.tok.Is(token.Shr) {
+		sp := span.New(p.tok.Sp.Start, p.tok.Sp.Start+1)
+		p.prevTok = token.New(token.Gt, "", sp)
+		p.tok = token.New(token.Gt, "", span.New(sp.End, p.tok.Sp.End))
+		return sp, true
+	}
+	return p.eat(token.Gt)
+}
+
 // next advances the parser to the next token in tokens.
 func (p *parser) next() {
 	if p.pos < len(p.tokens) {
@@ -1,5 +1,11 @@
 package span
 
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 type SourceFile struct {
 	Name  string
 	Src   []byte
@@ -57,6 +63,85 @@ func (f *SourceFile) LineSlice(lo, hi int) []byte {
 	return *new([]byte)
 }
 
+// Column returns the 1-based display column of pos on its line, where each
+// character counts as the number of cells it takes up in a terminal.
+func (f *SourceFile) Column(pos int) int {
+	return Width(f.linePrefix(pos)) + 1
+}
+
+// Pad returns blank text as wide as the text before pos on its line, with its tabs
+// kept as tabs, so that text printed after it below that line starts right under pos.
+func (f *SourceFile) Pad(pos int) string {
+	var builder strings.Builder
+	for _, r := range string(f.linePrefix(pos)) {
+		if r == '\t' {
+			builder.WriteByte('\t')
+		} else {
+			builder.WriteString(strings.Repeat(" ", RuneWidth(r)))
+		}
+	}
+	return builder.String()
+}
+
+// linePrefix returns the bytes of the line of pos that come before it.
+func (f *SourceFile) linePrefix(pos int) []byte {
+	if pos > len(f.Src) {
+		pos = len(f.Src)
+	}
+	return f.Src[f.LinePos(f.Line(pos)):pos]
+}
+
+// Width returns the number of terminal cells that UTF-8 encoded text b takes up.
+func Width(b []byte) int {
+	w := 0
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		w += RuneWidth(r)
+		b = b[size:]
+	}
+	return w
+}
+
+// RuneWidth returns the number of terminal cells that rune r takes up, which is
+// zero for combining marks and other invisible characters, two for wide East Asian
+// characters and emoji, and one for everything else.
+func RuneWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.Is(wide, r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// The characters that take up two cells in a terminal, i.e., those of
+// East Asian Width `W` or `F` in the most common blocks, including emoji.
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe4f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
 // lines returns all line beginnings in src.
 func lines(src []byte) []int {
 	lines := []int{0}
@@ -62,3 +62,45 @@ func TestLineSlice(t *testing.T) {
 		}
 	}
 }
+
+func TestColumn(t *testing.T) {
+	src := "ab\né x\n日本 x\n\tx\ne\u0301x"
+	f := NewSourceFile("filename", []byte(src))
+	cases := []struct {
+		in   int
+		want int
+	}{
+		{0, 1}, {2, 3},
+		{3, 1}, {5, 2}, {6, 3},
+		{8, 1}, {11, 3}, {14, 5}, {15, 6},
+		{17, 1}, {18, 2},
+		{20, 1}, {23, 2}, {24, 3},
+	}
+
+	for _, c := range cases {
+		got := f.Column(c.in)
+		if got != c.want {
+			t.Errorf("Column(%d) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	src := "é x\n日本 x\n\tx"
+	f := NewSourceFile("filename", []byte(src))
+	cases := []struct {
+		in   int
+		want string
+	}{
+		{0, ""}, {3, "  "},
+		{11, "    "}, {12, "     "},
+		{15, "\t"},
+	}
+
+	for _, c := range cases {
+		got := f.Pad(c.in)
+		if got != c.want {
+			t.Errorf("Pad(%d) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -9,80 +9,136 @@ import (
 type Kind int
 
 const (
-	Unknown Kind = iota // An unknown character to the lexer.
-	Eof                 // End of file.
-	Ident               // E.g., `foo`
-	Number              // E.g., `123`
-	String              // E.g., `"foo"`
-	Plus                // `+`
-	Minus               // `-`
-	Star                // `*`
-	Slash               // `/`
-	Eq                  // `=`
-	Gt                  // `>`
-	Lt                  // `<`
-	Ge                  // `>=`
-	Le                  // `<=`
-	EqEq                // `==`
-	Ne                  // `!=`
-	Colon               // `:`
-	Comma               // `,`
-	Dot                 // `.`
-	LParen              // `(`
-	LBrack              // `[`
-	LBrace              // `{`
-	RParen              // `)`
-	RBrack              // `]`
-	RBrace              // `}`
-	Break               // `break`
-	Class               // `class`
-	Else                // `else`
-	False               // `false`
-	Fn                  // `fn`
-	For                 // `for`
-	If                  // `if`
-	Let                 // `let`
-	Return              // `return`
-	True                // `true`
+	Unknown    Kind = iota // An unknown character to the lexer.
+	Eof                    // End of file.
+	Ident                  // E.g., `foo`
+	Number                 // E.g., `123`
+	Float                  // E.g., `1.5`
+	String                 // E.g., `"foo"`
+	StringHead             // Start of an interpolated string, e.g., `"foo {`
+	StringMid              // Part of an interpolated string between two expressions, e.g., `} bar {`
+	StringTail             // End of an interpolated string, e.g., `} baz"`
+	Label                  // E.g., `'outer`
+	Plus                   // `+`
+	Minus                  // `-`
+	Star                   // `*`
+	Slash                  // `/`
+	Percent                // `%`
+	Amp                    // `&`
+	Pipe                   // `|`
+	Caret                  // `^`
+	Shl                    // `<<`
+	Shr                    // `>>`
+	Bang                   // `!`
+	Eq                     // `=`
+	Gt                     // `>`
+	Lt                     // `<`
+	Ge                     // `>=`
+	Le                     // `<=`
+	EqEq                   // `==`
+	FatArrow               // `=>`
+	Ne                     // `!=`
+	AmpAmp                 // `&&`
+	PipePipe               // `||`
+	PlusEq                 // `+=`
+	MinusEq                // `-=`
+	StarEq                 // `*=`
+	SlashEq                // `/=`
+	Colon                  // `:`
+	Comma                  // `,`
+	Dot                    // `.`
+	DotDot                 // `..`
+	DotDotEq               // `..=`
+	Question               // `?`
+	LParen                 // `(`
+	LBrack                 // `[`
+	LBrace                 // `{`
+	RParen                 // `)`
+	RBrack                 // `]`
+	RBrace                 // `}`
+	Break                  // `break`
+	Class                  // `class`
+	Continue               // `continue`
+	Else                   // `else`
+	Enum                   // `enum`
+	False                  // `false`
+	Fn                     // `fn`
+	For                    // `for`
+	If                     // `if`
+	In                     // `in`
+	Let                    // `let`
+	Match                  // `match`
+	Nil                    // `nil`
+	Return                 // `return`
+	Self                   // `self`
+	True                   // `true`
 	end
 )
 
 var tokens = [...]string{
-	Unknown: "unknown",
-	Eof:     "eof",
-	Ident:   "identifier",
-	Number:  "number",
-	String:  "string",
-	Plus:    "+",
-	Minus:   "-",
-	Star:    "*",
-	Slash:   "/",
-	Eq:      "=",
-	Gt:      ">",
-	Lt:      "<",
-	Ge:      ">=",
-	Le:      ">=",
-	EqEq:    "==",
-	Ne:      "!=",
-	Colon:   ":",
-	Comma:   ",",
-	Dot:     ".",
-	LParen:  "(",
-	LBrack:  "[",
-	LBrace:  "{",
-	RParen:  ")",
-	RBrack:  "]",
-	RBrace:  "}",
-	Break:   "break",
-	Class:   "class",
-	Else:    "else",
-	False:   "false",
-	Fn:      "fn",
-	For:     "for",
-	If:      "if",
-	Let:     "let",
-	Return:  "return",
-	True:    "true",
+	Unknown:    "unknown",
+	Eof:        "eof",
+	Ident:      "identifier",
+	Number:     "number",
+	Float:      "float",
+	String:     "string",
+	StringHead: "string",
+	StringMid:  "string",
+	StringTail: "string",
+	Label:      "label",
+	Plus:       "+",
+	Minus:      "-",
+	Star:       "*",
+	Slash:      "/",
+	Percent:    "%",
+	Amp:        "&",
+	Pipe:       "|",
+	Caret:      "^",
+	Shl:        "<<",
+	Shr:        ">>",
+	Bang:       "!",
+	Eq:         "=",
+	Gt:         ">",
+	Lt:         "<",
+	Ge:         ">=",
+	Le:         ">=",
+	EqEq:       "==",
+	FatArrow:   "=>",
+	Ne:         "!=",
+	AmpAmp:     "&&",
+	PipePipe:   "||",
+	PlusEq:     "+=",
+	MinusEq:    "-=",
+	StarEq:     "*=",
+	SlashEq:    "/=",
+	Colon:      ":",
+	Comma:      ",",
+	Dot:        ".",
+	DotDot:     "..",
+	DotDotEq:   "..=",
+	Question:   "?",
+	LParen:     "(",
+	LBrack:     "[",
+	LBrace:     "{",
+	RParen:     ")",
+	RBrack:     "]",
+	RBrace:     "}",
+	Break:      "break",
+	Class:      "class",
+	Continue:   "continue",
+	Else:       "else",
+	Enum:       "enum",
+	False:      "false",
+	Fn:         "fn",
+	For:        "for",
+	If:         "if",
+	In:         "in",
+	Let:        "let",
+	Match:      "match",
+	Nil:        "nil",
+	Return:     "return",
+	Self:       "self",
+	True:       "true",
 }
 
 func (k Kind) String() string {
@@ -99,7 +155,7 @@ func (k Kind) Ok() bool {
 
 type Token struct {
 	Kind Kind
-	Lit  string // Literal value of token if kind is `Unknown` or `Number`, otherwise empty.
+	Lit  string // Literal value of token if kind is `Unknown`, `Number`, `Label` or a string, otherwise empty.
 	Sp   span.Span
 }
 
@@ -123,16 +179,22 @@ func (t Token) IsOneOf(ks ...Kind) bool {
 }
 
 var keywords = map[string]Kind{
-	"class":  Class,
-	"break":  Break,
-	"else":   Else,
-	"false":  False,
-	"fn":     Fn,
-	"for":    For,
-	"if":     If,
-	"let":    Let,
-	"return": Return,
-	"true":   True,
+	"class":    Class,
+	"break":    Break,
+	"continue": Continue,
+	"else":     Else,
+	"enum":     Enum,
+	"false":    False,
+	"fn":       Fn,
+	"for":      For,
+	"if":       If,
+	"in":       In,
+	"let":      Let,
+	"match":    Match,
+	"nil":      Nil,
+	"return":   Return,
+	"self":     Self,
+	"true":     True,
 }
 
 // Lookup returns the associated token kind for ident.